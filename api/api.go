@@ -2,6 +2,7 @@ package api
 
 import (
 	"context"
+	"fmt"
 	"image"
 
 	"github.com/hashicorp/go-hclog"
@@ -13,8 +14,17 @@ type ItemHitHint int
 type Match int
 
 const (
-	MatchAny Match = iota
-	MatchFuzzy
+	MatchAny     Match = iota // Every item matches
+	MatchFuzzy                // The query matches the characters of the label in order
+	MatchPrefix               // The label starts with the query
+	MatchWord                 // Every word of the query is a word of the label
+	MatchOrdered              // Like MatchFuzzy, but the items keep the order in which they were suggested
+)
+
+const (
+	HitNormal ItemHitHint = iota // The item is ranked by its score
+	HitBoost                     // The item ranks above items that match equally well
+	HitTop                       // The item ranks above all items without this hint, e.g. the result of a calculation
 )
 
 const (
@@ -31,31 +41,93 @@ const (
 	Required
 )
 
+// Action is an alternative way of executing an item, such as copying its URL instead of opening it
+type Action struct {
+	ID    string // Identifies the action when it is passed to Plugin.Execute
+	Label string
+}
+
+// DefaultAction identifies the default action of an item, which is executed with Enter
+const DefaultAction = ""
+
 type Item struct {
+	// ID identifies the item within its plugin, it should be the same for the same item across catalog rebuilds and
+	// sessions. When empty, Key derives one from Target and Label.
+	ID          string
 	Label       string
 	Description string
 	Category    ItemCategory
 	Target      string
-	Data        interface{}
-	Icon        *image.Image
-	ArgsHint    ItemArgsHint
+
+	// Data is passed back to the plugin when the item is executed or pushed. The catalog is cached as JSON, so the
+	// Data of catalog items must decode from JSON to the same value, in practice a string. Catalog items with other
+	// Data are not cached.
+	Data interface{}
+
+	Icon     *image.Image
+	ArgsHint ItemArgsHint
+	Actions  []Action // Actions of the item besides its default action
+
+	// Keywords are alternative names the item can be found by besides its label, e.g. the executable of an app. They
+	// match with a lower score than the label.
+	Keywords []string
+
+	// Score is the relevance of the item between 0.0 and 1.0 as determined by the plugin, it replaces the score of
+	// matching the query when set. HitHint lets the item rank higher than its score.
+	Score   float64
+	HitHint ItemHitHint
 }
 
 func (i Item) String() string {
 	return i.Label
 }
 
+// NewErrorItem returns an item that reports the error to the user, e.g. "github: 401 Bad credentials"
+func NewErrorItem(source string, err error) Item {
+	return Item{
+		ID:       "error:" + source,
+		Label:    fmt.Sprintf("%s: %s", source, err),
+		Category: Error,
+		ArgsHint: Forbidden,
+	}
+}
+
+// Key returns the ID of the item or, if it has none, an identifier derived from its Target and Label
+func (i Item) Key() string {
+	if i.ID != "" {
+		return i.ID
+	} else if i.Target == "" {
+		return i.Label
+	}
+
+	return i.Target + "|" + i.Label
+}
+
 type SuggestionCallback func([]Item, Match)
 
+// CatalogCallback adds a batch of items to the catalog that a plugin is building, it may be called any number of times
+type CatalogCallback func([]Item)
+
 type Plugin interface {
 	Initialize(log hclog.Logger)
-	Catalog(ctx context.Context) error
+	// Catalog builds the items that are searched at the root. The items are passed to the callback in batches as soon
+	// as they are found, the batches a plugin delivered before failing are kept.
+	Catalog(ctx context.Context, callback CatalogCallback) error
+	// Suggest delivers the suggestions for the input to the callback before it returns, suggestions that are delivered
+	// later are dropped
 	Suggest(ctx context.Context, input string, chain []Item, callback SuggestionCallback)
 	Icon() *image.Image
-	GetItems() ([]Item, error)
-	Execute(Item)
+	// Execute executes the action with the given ID of the item, which is DefaultAction when Enter was pressed
+	Execute(ctx context.Context, item Item, action string) error
 	Name() string
 
 	// LoadConfig receives a loader function that when invoked loads the configuration in the struct pointer `config` that it's passed
 	LoadConfig(func(interface{}) error)
 }
+
+// ConfigChecker is implemented by plugins that have settings of their own. NewConfig returns a pointer to an empty
+// value of the type LoadConfig loads the section into, so the configuration can be checked without an initialized
+// plugin.
+type ConfigChecker interface {
+	NewConfig() interface{}
+}
@@ -0,0 +1,13 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestItem_Key(t *testing.T) {
+	assert.Equal(t, "repo", Item{ID: "repo", Label: "Repository", Target: "https://example.com"}.Key())
+	assert.Equal(t, "https://example.com|Repository", Item{Label: "Repository", Target: "https://example.com"}.Key())
+	assert.Equal(t, "Repository", Item{Label: "Repository"}.Key())
+}
@@ -0,0 +1,30 @@
+package api
+
+import (
+	"errors"
+	"sync"
+
+	"golang.design/x/clipboard"
+)
+
+var (
+	clipboardOnce sync.Once
+	clipboardErr  error
+)
+
+// CopyToClipboard writes the text to the clipboard, it returns an error when the clipboard is not available
+func CopyToClipboard(text string) error {
+	clipboardOnce.Do(func() {
+		clipboardErr = clipboard.Init()
+	})
+
+	if clipboardErr != nil {
+		return clipboardErr
+	}
+
+	if clipboard.Write(clipboard.FmtText, []byte(text)) == nil {
+		return errors.New("could not write to the clipboard")
+	}
+
+	return nil
+}
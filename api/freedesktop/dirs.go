@@ -0,0 +1,71 @@
+// Package freedesktop implements the freedesktop.org specifications the launcher needs on Linux: the base
+// directories, desktop entries, icon themes, the shared MIME database and the associations of MIME types with
+// applications
+package freedesktop
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// DataHome returns $XDG_DATA_HOME, ~/.local/share by default
+func DataHome() string {
+	return fromEnv("XDG_DATA_HOME", ".local", "share")
+}
+
+// ConfigHome returns $XDG_CONFIG_HOME, ~/.config by default
+func ConfigHome() string {
+	return fromEnv("XDG_CONFIG_HOME", ".config")
+}
+
+// CacheHome returns $XDG_CACHE_HOME, ~/.cache by default
+func CacheHome() string {
+	return fromEnv("XDG_CACHE_HOME", ".cache")
+}
+
+func fromEnv(variable string, fallback ...string) string {
+	if dir := os.Getenv(variable); len(dir) > 0 {
+		return dir
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(append([]string{home}, fallback...)...)
+}
+
+// DataDirs returns the data home followed by $XDG_DATA_DIRS, from the most to the least important
+func DataDirs() []string {
+	dirs := os.Getenv("XDG_DATA_DIRS")
+	if len(dirs) == 0 {
+		dirs = "/usr/local/share:/usr/share"
+	}
+
+	var result []string
+	for _, dir := range append([]string{DataHome()}, filepath.SplitList(dirs)...) {
+		if len(dir) > 0 {
+			result = append(result, dir)
+		}
+	}
+
+	return result
+}
+
+// ConfigDirs returns the config home followed by $XDG_CONFIG_DIRS, from the most to the least important
+func ConfigDirs() []string {
+	dirs := os.Getenv("XDG_CONFIG_DIRS")
+	if len(dirs) == 0 {
+		dirs = "/etc/xdg"
+	}
+
+	var result []string
+	for _, dir := range append([]string{ConfigHome()}, filepath.SplitList(dirs)...) {
+		if len(dir) > 0 {
+			result = append(result, dir)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,253 @@
+package freedesktop
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Entry is a desktop entry of type Application, see the Desktop Entry Specification
+type Entry struct {
+	ID   string // The desktop file ID, e.g. org.gnome.Terminal.desktop
+	File string // The file the entry was read from
+
+	Type        string
+	Name        string // Localized
+	GenericName string // Localized, e.g. "Web Browser"
+	Comment     string // Localized
+	Keywords    []string
+	Icon        string
+	Exec        string
+	TryExec     string
+	Path        string // The working directory
+	Terminal    bool
+	NoDisplay   bool
+	Hidden      bool // The entry is deleted, it hides the entries with the same ID in the directories that follow
+	OnlyShowIn  []string
+	NotShowIn   []string
+	Actions     []Action
+}
+
+// Action is an additional way of launching the application, e.g. opening a private browser window
+type Action struct {
+	ID   string
+	Name string // Localized
+	Icon string
+	Exec string
+}
+
+const desktopEntryGroup = "Desktop Entry"
+
+// ApplicationDirs returns the directories that contain desktop entries, from the most to the least important
+func ApplicationDirs() []string {
+	var result []string
+	for _, dir := range DataDirs() {
+		result = append(result, filepath.Join(dir, "applications"))
+	}
+
+	return result
+}
+
+// FindEntry returns the file of the desktop entry with the desktop file ID. The ID of applications/vendor/app.desktop
+// is vendor-app.desktop.
+func FindEntry(id string) (string, bool) {
+	for _, dir := range ApplicationDirs() {
+		candidates := []string{id}
+		for idx := strings.IndexByte(id, '-'); idx >= 0; idx = nextIndex(id, '-', idx) {
+			candidates = append(candidates, id[:idx]+string(filepath.Separator)+id[idx+1:])
+		}
+
+		for _, candidate := range candidates {
+			file := filepath.Join(dir, candidate)
+			if _, err := os.Stat(file); err == nil {
+				return file, true
+			}
+		}
+	}
+
+	return "", false
+}
+
+func nextIndex(s string, b byte, after int) int {
+	if idx := strings.IndexByte(s[after+1:], b); idx >= 0 {
+		return after + 1 + idx
+	}
+
+	return -1
+}
+
+// group holds the keys of a group of a desktop entry file, the keys of localized values include their locale, e.g.
+// Name[de]
+type group map[string]string
+
+// ReadEntry reads the desktop entry file, the values of localized keys are picked for the locales, which are ordered
+// from the most to the least specific
+func ReadEntry(file string, id string, locales []string) (Entry, error) {
+	groups, err := ReadKeyFile(file)
+	if err != nil {
+		return Entry{}, err
+	}
+
+	values, found := groups[desktopEntryGroup]
+	if !found {
+		return Entry{}, fmt.Errorf("%s: no [%s] group", file, desktopEntryGroup)
+	}
+	main := group(values)
+
+	e := Entry{
+		ID:          id,
+		File:        file,
+		Type:        main.text("Type"),
+		Name:        main.localized("Name", locales),
+		GenericName: main.localized("GenericName", locales),
+		Comment:     main.localized("Comment", locales),
+		Keywords:    splitList(main.localizedRaw("Keywords", locales)),
+		Icon:        main.text("Icon"),
+		Exec:        main.text("Exec"),
+		TryExec:     main.text("TryExec"),
+		Path:        main.text("Path"),
+		Terminal:    main.boolean("Terminal"),
+		NoDisplay:   main.boolean("NoDisplay"),
+		Hidden:      main.boolean("Hidden"),
+		OnlyShowIn:  splitList(main["OnlyShowIn"]),
+		NotShowIn:   splitList(main["NotShowIn"]),
+	}
+
+	for _, id := range splitList(main["Actions"]) {
+		values, found := groups["Desktop Action "+id]
+		if !found {
+			continue
+		}
+		action := group(values)
+
+		e.Actions = append(e.Actions, Action{
+			ID:   id,
+			Name: action.localized("Name", locales),
+			Icon: action.text("Icon"),
+			Exec: action.text("Exec"),
+		})
+	}
+
+	return e, nil
+}
+
+func (g group) text(key string) string {
+	return unescape(g[key])
+}
+
+func (g group) boolean(key string) bool {
+	return g[key] == "true"
+}
+
+// localizedRaw returns the value of the key for the first locale that has one, or the value without locale
+func (g group) localizedRaw(key string, locales []string) string {
+	for _, locale := range locales {
+		if value, found := g[key+"["+locale+"]"]; found {
+			return value
+		}
+	}
+
+	return g[key]
+}
+
+func (g group) localized(key string, locales []string) string {
+	return unescape(g.localizedRaw(key, locales))
+}
+
+// unescape replaces the escape sequences of string values: \s, \n, \t, \r and \\
+func unescape(value string) string {
+	if !strings.Contains(value, `\`) {
+		return value
+	}
+
+	var b strings.Builder
+	for idx := 0; idx < len(value); idx++ {
+		if value[idx] != '\\' || idx+1 == len(value) {
+			b.WriteByte(value[idx])
+			continue
+		}
+
+		idx++
+		switch value[idx] {
+		case 's':
+			b.WriteByte(' ')
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		case 'r':
+			b.WriteByte('\r')
+		case '\\':
+			b.WriteByte('\\')
+		default:
+			// Other escapes, like the quoting of Exec, are kept for later
+			b.WriteByte('\\')
+			b.WriteByte(value[idx])
+		}
+	}
+
+	return b.String()
+}
+
+// splitList splits a list value at its semicolons, \; is a semicolon within a value
+func splitList(value string) []string {
+	var result []string
+	var b strings.Builder
+
+	for idx := 0; idx < len(value); idx++ {
+		switch {
+		case value[idx] == '\\' && idx+1 < len(value) && value[idx+1] == ';':
+			b.WriteByte(';')
+			idx++
+		case value[idx] == ';':
+			if s := unescape(strings.TrimSpace(b.String())); len(s) > 0 {
+				result = append(result, s)
+			}
+			b.Reset()
+		default:
+			b.WriteByte(value[idx])
+		}
+	}
+
+	if s := unescape(strings.TrimSpace(b.String())); len(s) > 0 {
+		result = append(result, s)
+	}
+
+	return result
+}
+
+// Locales returns the locales of the messages of the user from the most to the least specific, e.g. for
+// "de_DE.UTF-8@euro" these are de_DE@euro, de_DE, de@euro and de
+func Locales() []string {
+	for _, variable := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if value := os.Getenv(variable); len(value) > 0 {
+			return localeVariants(value)
+		}
+	}
+
+	return nil
+}
+
+func localeVariants(locale string) []string {
+	locale, modifier, _ := strings.Cut(locale, "@")
+	locale, _, _ = strings.Cut(locale, ".")
+	lang, country, _ := strings.Cut(locale, "_")
+
+	if len(lang) == 0 || lang == "C" || lang == "POSIX" {
+		return nil
+	}
+
+	var result []string
+	if len(country) > 0 && len(modifier) > 0 {
+		result = append(result, lang+"_"+country+"@"+modifier)
+	}
+	if len(country) > 0 {
+		result = append(result, lang+"_"+country)
+	}
+	if len(modifier) > 0 {
+		result = append(result, lang+"@"+modifier)
+	}
+
+	return append(result, lang)
+}
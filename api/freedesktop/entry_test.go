@@ -0,0 +1,27 @@
+package freedesktop
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitExec_Invalid(t *testing.T) {
+	_, err := splitExec(`editor "unterminated`)
+	assert.EqualError(t, err, `unterminated quote in "editor \"unterminated"`)
+
+	_, err = splitExec(" ")
+	assert.Error(t, err)
+}
+
+func TestSplitList(t *testing.T) {
+	assert.Equal(t, []string{"a;b", "c d"}, splitList(`a\;b;c\sd;`))
+	assert.Nil(t, splitList(""))
+}
+
+func TestLocaleVariants(t *testing.T) {
+	assert.Equal(t, []string{"sr_YU@Latn", "sr_YU", "sr@Latn", "sr"}, localeVariants("sr_YU.UTF-8@Latn"))
+	assert.Equal(t, []string{"de_DE", "de"}, localeVariants("de_DE.UTF-8"))
+	assert.Equal(t, []string{"fr"}, localeVariants("fr"))
+	assert.Nil(t, localeVariants("C.UTF-8"))
+}
@@ -0,0 +1,177 @@
+package freedesktop
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// splitExec splits the Exec value of an entry into its arguments. Arguments are separated by spaces, an argument in
+// double quotes may contain spaces and escapes ", `, $ and \ with a backslash.
+func splitExec(exec string) ([]string, error) {
+	var result []string
+	var b strings.Builder
+	inArgument, quoted := false, false
+
+	for idx := 0; idx < len(exec); idx++ {
+		c := exec[idx]
+
+		switch {
+		case quoted && c == '\\' && idx+1 < len(exec) && strings.IndexByte("\"`$\\", exec[idx+1]) >= 0:
+			idx++
+			b.WriteByte(exec[idx])
+		case quoted && c == '"':
+			quoted = false
+		case quoted:
+			b.WriteByte(c)
+		case c == '"':
+			quoted, inArgument = true, true
+		case c == ' ' || c == '\t':
+			if inArgument {
+				result = append(result, b.String())
+				b.Reset()
+				inArgument = false
+			}
+		default:
+			b.WriteByte(c)
+			inArgument = true
+		}
+	}
+
+	if quoted {
+		return nil, fmt.Errorf("unterminated quote in %q", exec)
+	}
+	if inArgument {
+		result = append(result, b.String())
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("no program in %q", exec)
+	}
+
+	return result, nil
+}
+
+// Program returns the program that the entry runs, it returns false if the Exec value is invalid
+func (e Entry) Program() (string, bool) {
+	args, err := splitExec(e.Exec)
+	if err != nil {
+		return "", false
+	}
+
+	return args[0], true
+}
+
+// expandExec returns the command line of the Exec value with its field codes expanded for the files or URLs, which
+// may be empty. The codes for a list of files are only expanded when they are an argument on their own.
+func expandExec(exec string, e Entry, files []string) ([]string, error) {
+	args, err := splitExec(exec)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []string
+	for _, arg := range args {
+		switch arg {
+		case "%F", "%U":
+			result = append(result, files...)
+			continue
+		case "%i":
+			if len(e.Icon) > 0 {
+				result = append(result, "--icon", e.Icon)
+			}
+			continue
+		}
+
+		var b strings.Builder
+		for idx := 0; idx < len(arg); idx++ {
+			if arg[idx] != '%' || idx+1 == len(arg) {
+				b.WriteByte(arg[idx])
+				continue
+			}
+
+			idx++
+			switch arg[idx] {
+			case '%':
+				b.WriteByte('%')
+			case 'f', 'u':
+				if len(files) > 0 {
+					b.WriteString(files[0])
+				}
+			case 'c':
+				b.WriteString(e.Name)
+			case 'k':
+				b.WriteString(e.File)
+			default:
+				// %F and %U within an argument, %i within an argument and the deprecated codes are removed
+			}
+		}
+
+		// An argument that only consisted of field codes without a value is dropped
+		if b.Len() > 0 || !strings.HasPrefix(arg, "%") {
+			result = append(result, b.String())
+		}
+	}
+
+	return result, nil
+}
+
+// Command returns the command line that launches the action of the entry, or the entry itself if the action is empty,
+// with the files or URLs to open. Applications that run in a terminal are wrapped in a terminal emulator.
+func Command(e Entry, action string, files []string) ([]string, error) {
+	command := e.Exec
+
+	if len(action) > 0 {
+		command = ""
+		for _, each := range e.Actions {
+			if each.ID == action {
+				command = each.Exec
+			}
+		}
+
+		if len(command) == 0 {
+			return nil, fmt.Errorf("application %s has no action %s", e.ID, action)
+		}
+	}
+
+	args, err := expandExec(command, e, files)
+	if err != nil {
+		return nil, fmt.Errorf("application %s: %w", e.ID, err)
+	}
+
+	if e.Terminal {
+		return terminalCommand(args)
+	}
+
+	return args, nil
+}
+
+// terminals are the terminal emulators that are tried, with the option that runs a command
+var terminals = []struct {
+	program string
+	option  string
+}{
+	{"x-terminal-emulator", "-e"},
+	{"gnome-terminal", "--"},
+	{"konsole", "-e"},
+	{"xfce4-terminal", "-x"},
+	{"alacritty", "-e"},
+	{"kitty", "--"},
+	{"xterm", "-e"},
+}
+
+// terminalCommand wraps the command in the terminal of $TERMINAL, or the first terminal emulator that is installed
+func terminalCommand(args []string) ([]string, error) {
+	if terminal := os.Getenv("TERMINAL"); len(terminal) > 0 {
+		return append([]string{terminal, "-e"}, args...), nil
+	}
+
+	for _, each := range terminals {
+		if _, err := exec.LookPath(each.program); err == nil {
+			return append([]string{each.program, each.option}, args...), nil
+		}
+	}
+
+	return nil, errors.New("no terminal emulator found, set $TERMINAL")
+}
@@ -0,0 +1,194 @@
+package freedesktop
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/image/draw"
+)
+
+// baseIconSize is the size of the icons of items in pixels on a display without scaling
+const baseIconSize = 16
+
+// IconSize returns the size of the icons of items in pixels, scaled for the display by $GDK_SCALE or
+// $QT_SCALE_FACTOR
+func IconSize() int {
+	scale := 1.0
+	for _, variable := range []string{"GDK_SCALE", "QT_SCALE_FACTOR"} {
+		if value, err := strconv.ParseFloat(os.Getenv(variable), 64); err == nil && value > 0 {
+			scale = value
+			break
+		}
+	}
+
+	return int(math.Round(baseIconSize * scale))
+}
+
+var icons = struct {
+	mutex    sync.Mutex
+	images   map[string]image.Image // By icon file and size
+	cacheDir string
+}{images: make(map[string]image.Image)}
+
+// SetIconCacheDir sets the directory the rendered icons are cached in, no icons are cached on disk without one
+func SetIconCacheDir(dir string) {
+	icons.mutex.Lock()
+	icons.cacheDir = dir
+	icons.mutex.Unlock()
+}
+
+// FileIcon returns the icon of the file in size×size pixels from the current icon theme: the Icon of a desktop entry,
+// or the icon of the MIME type of any other file
+func FileIcon(file string, size int) (image.Image, error) {
+	var names []string
+
+	if filepath.Ext(file) == ".desktop" {
+		entry, err := ReadKeyFile(file)
+		if err != nil {
+			return nil, err
+		}
+		if icon := entry["Desktop Entry"]["Icon"]; len(icon) > 0 {
+			names = append(names, icon)
+		}
+		names = append(names, "application-x-executable")
+	} else {
+		db := Mime()
+		names = db.IconNames(db.TypeOfFile(file))
+		if strings.HasPrefix(names[0], "inode-directory") {
+			names = append([]string{"folder"}, names...)
+		}
+	}
+
+	return NamedIcon(names, size)
+}
+
+// NamedIcon returns the first of the icons that the current icon theme has, in size×size pixels
+func NamedIcon(names []string, size int) (image.Image, error) {
+	theme := CurrentTheme()
+
+	for _, name := range names {
+		if file, found := FindIcon(theme, name, size); found {
+			return LoadIcon(file, size)
+		}
+	}
+
+	return nil, fmt.Errorf("no icon %s in theme %s", strings.Join(names, ", "), theme)
+}
+
+// LoadIcon returns the PNG or SVG file as an image of size×size pixels. Icons are cached in memory, and on disk if
+// there is a cache directory.
+func LoadIcon(file string, size int) (image.Image, error) {
+	key := file + "@" + strconv.Itoa(size)
+
+	icons.mutex.Lock()
+	im, found := icons.images[key]
+	cacheDir := icons.cacheDir
+	icons.mutex.Unlock()
+
+	if found {
+		return im, nil
+	}
+
+	info, err := os.Stat(file)
+	if err != nil {
+		return nil, err
+	}
+
+	// The file name of the cached icon changes with the icon
+	var cached string
+	if len(cacheDir) > 0 {
+		hash := sha1.Sum([]byte(fmt.Sprintf("%s\x00%d\x00%d", file, info.ModTime().UnixNano(), size)))
+		cached = filepath.Join(cacheDir, hex.EncodeToString(hash[:])+".png")
+	}
+
+	if im, err = readPNG(cached); err != nil {
+		if im, err = renderIcon(file, size); err != nil {
+			return nil, err
+		}
+
+		if len(cached) > 0 {
+			// The icon is rendered again next time if it cannot be cached
+			_ = writePNG(cached, im)
+		}
+	}
+
+	icons.mutex.Lock()
+	icons.images[key] = im
+	icons.mutex.Unlock()
+
+	return im, nil
+}
+
+func renderIcon(file string, size int) (image.Image, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if filepath.Ext(file) == ".svg" {
+		im, err := RasterizeSVG(f, size)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		return im, nil
+	}
+
+	im, err := png.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	if im.Bounds().Dx() == size && im.Bounds().Dy() == size {
+		return im, nil
+	}
+
+	scaled := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.CatmullRom.Scale(scaled, scaled.Bounds(), im, im.Bounds(), draw.Over, nil)
+	return scaled, nil
+}
+
+func readPNG(file string) (image.Image, error) {
+	if len(file) == 0 {
+		return nil, os.ErrNotExist
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return png.Decode(f)
+}
+
+func writePNG(file string, im image.Image) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+
+	// The icon is written next to the file and renamed, so that nobody reads half of it
+	f, err := os.CreateTemp(filepath.Dir(file), "icon-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if err := png.Encode(f, im); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), file)
+}
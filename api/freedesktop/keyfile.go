@@ -0,0 +1,78 @@
+package freedesktop
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// KeyFile holds the groups of a file in the format of desktop entries, index.theme and mimeapps.list. The keys of
+// localized values include their locale, e.g. Name[de].
+type KeyFile map[string]map[string]string
+
+// ParseKeyFile reads the groups of a key file, the first occurrence of a key in a group wins
+func ParseKeyFile(r io.Reader) (KeyFile, error) {
+	result := make(KeyFile)
+	var current map[string]string
+
+	scanner := bufio.NewScanner(r)
+	for number := 1; scanner.Scan(); number++ {
+		line := strings.TrimSpace(scanner.Text())
+
+		switch {
+		case len(line) == 0 || strings.HasPrefix(line, "#"):
+			continue
+		case strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]"):
+			name := line[1 : len(line)-1]
+			if _, found := result[name]; !found {
+				result[name] = make(map[string]string)
+			}
+			current = result[name]
+		default:
+			key, value, found := strings.Cut(line, "=")
+			if !found {
+				return nil, fmt.Errorf("line %d: expected a key and a value", number)
+			} else if current == nil {
+				return nil, fmt.Errorf("line %d: key outside of a group", number)
+			}
+
+			key = strings.TrimSpace(key)
+			if _, exists := current[key]; !exists {
+				current[key] = strings.TrimSpace(value)
+			}
+		}
+	}
+
+	return result, scanner.Err()
+}
+
+// ReadKeyFile reads the groups of the key file
+func ReadKeyFile(file string) (KeyFile, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	result, err := ParseKeyFile(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+
+	return result, nil
+}
+
+// List splits a list value at its semicolons or commas, empty elements are dropped
+func List(value string) []string {
+	var result []string
+
+	for _, each := range strings.FieldsFunc(value, func(r rune) bool { return r == ';' || r == ',' }) {
+		if each = strings.TrimSpace(each); len(each) > 0 {
+			result = append(result, each)
+		}
+	}
+
+	return result
+}
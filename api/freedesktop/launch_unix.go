@@ -0,0 +1,30 @@
+//go:build !windows
+
+package freedesktop
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// Launch starts the application in a session of its own, so it keeps running when the launcher exits
+func Launch(args []string, dir string) error {
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Dir = dir
+	detach(cmd)
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	// The application is not waited for, but it should not become a zombie when it exits
+	go cmd.Wait()
+
+	return nil
+}
+
+// detach makes the command the leader of a new session, so it neither gets the signals of the launcher nor dies with
+// it
+func detach(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+}
@@ -0,0 +1,14 @@
+package freedesktop
+
+import (
+	"errors"
+	"os/exec"
+)
+
+func Launch(args []string, dir string) error {
+	return errors.New("desktop entries cannot be launched on Windows")
+}
+
+func detach(cmd *exec.Cmd) {
+	// Windows does not run desktop entries
+}
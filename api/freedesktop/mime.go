@@ -0,0 +1,271 @@
+package freedesktop
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// MimeDatabase holds the parts of the shared MIME-info database of the data dirs that are needed to detect the type of
+// files by their names and to find their icons and applications
+type MimeDatabase struct {
+	globs        []mimeGlob
+	aliases      map[string]string   // The canonical type of an alias
+	parents      map[string][]string // The types a type is a subclass of
+	icons        map[string]string
+	genericIcons map[string]string
+}
+
+type mimeGlob struct {
+	weight        int
+	mimeType      string
+	pattern       string
+	caseSensitive bool
+}
+
+var mimeDatabase = struct {
+	once sync.Once
+	db   *MimeDatabase
+}{}
+
+// Mime returns the database of the data dirs, it is read once
+func Mime() *MimeDatabase {
+	mimeDatabase.once.Do(func() {
+		mimeDatabase.db = ReadMimeDatabase(DataDirs())
+	})
+
+	return mimeDatabase.db
+}
+
+// ReadMimeDatabase reads the database in the mime directories of the data dirs, which are ordered from the most to the
+// least important. Missing files are skipped.
+func ReadMimeDatabase(dataDirs []string) *MimeDatabase {
+	db := &MimeDatabase{
+		aliases:      make(map[string]string),
+		parents:      make(map[string][]string),
+		icons:        make(map[string]string),
+		genericIcons: make(map[string]string),
+	}
+
+	for _, dir := range dataDirs {
+		dir = filepath.Join(dir, "mime")
+
+		readLines(filepath.Join(dir, "globs2"), func(line string) {
+			fields := strings.Split(line, ":")
+			if len(fields) < 3 {
+				return
+			}
+			weight, err := strconv.Atoi(fields[0])
+			if err != nil {
+				return
+			}
+
+			glob := mimeGlob{weight: weight, mimeType: fields[1], pattern: fields[2]}
+			if len(fields) > 3 {
+				glob.caseSensitive = strings.Contains(fields[3], "cs")
+			}
+			if !glob.caseSensitive {
+				glob.pattern = strings.ToLower(glob.pattern)
+			}
+			db.globs = append(db.globs, glob)
+		})
+
+		readPairs(filepath.Join(dir, "aliases"), " ", func(alias string, mimeType string) {
+			if _, found := db.aliases[alias]; !found {
+				db.aliases[alias] = mimeType
+			}
+		})
+		readPairs(filepath.Join(dir, "subclasses"), " ", func(mimeType string, parent string) {
+			db.parents[mimeType] = append(db.parents[mimeType], parent)
+		})
+		readPairs(filepath.Join(dir, "icons"), ":", func(mimeType string, icon string) {
+			if _, found := db.icons[mimeType]; !found {
+				db.icons[mimeType] = icon
+			}
+		})
+		readPairs(filepath.Join(dir, "generic-icons"), ":", func(mimeType string, icon string) {
+			if _, found := db.genericIcons[mimeType]; !found {
+				db.genericIcons[mimeType] = icon
+			}
+		})
+	}
+
+	return db
+}
+
+// readLines calls f with the lines of the file that are no comments
+func readLines(file string, f func(line string)) {
+	r, err := os.Open(file)
+	if err != nil {
+		return
+	}
+	defer r.Close()
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); len(line) > 0 && !strings.HasPrefix(line, "#") {
+			f(line)
+		}
+	}
+}
+
+func readPairs(file string, separator string, f func(key string, value string)) {
+	readLines(file, func(line string) {
+		if key, value, found := strings.Cut(line, separator); found {
+			f(strings.TrimSpace(key), strings.TrimSpace(value))
+		}
+	})
+}
+
+// Unalias returns the canonical name of the type
+func (db *MimeDatabase) Unalias(mimeType string) string {
+	if canonical, found := db.aliases[mimeType]; found {
+		return canonical
+	}
+
+	return mimeType
+}
+
+// TypeByName returns the type of the file name by the globs of the database, the glob with the highest weight and
+// then the longest pattern wins. It returns false if no glob matches.
+func (db *MimeDatabase) TypeByName(name string) (string, bool) {
+	name = filepath.Base(name)
+	lower := strings.ToLower(name)
+
+	var best *mimeGlob
+	for idx := range db.globs {
+		glob := &db.globs[idx]
+
+		candidate := lower
+		if glob.caseSensitive {
+			candidate = name
+		}
+		if matched, err := filepath.Match(glob.pattern, candidate); err != nil || !matched {
+			continue
+		}
+
+		if best == nil || glob.weight > best.weight || glob.weight == best.weight && len(glob.pattern) > len(best.pattern) {
+			best = glob
+		}
+	}
+
+	if best == nil {
+		return "", false
+	}
+
+	return best.mimeType, true
+}
+
+// TypeOfFile returns the type of the file: by its name, or else inode/directory, application/x-executable, text/plain
+// for files that look like text and application/octet-stream for everything else
+func (db *MimeDatabase) TypeOfFile(file string) string {
+	info, err := os.Stat(file)
+	if err == nil && info.IsDir() {
+		return "inode/directory"
+	}
+
+	if mimeType, found := db.TypeByName(file); found {
+		return mimeType
+	}
+	if err != nil {
+		return "application/octet-stream"
+	}
+	if info.Mode()&0111 != 0 {
+		return "application/x-executable"
+	}
+
+	if looksLikeText(file) {
+		return "text/plain"
+	}
+
+	return "application/octet-stream"
+}
+
+// looksLikeText returns true if the beginning of the file is UTF-8 without control characters other than whitespace
+func looksLikeText(file string) bool {
+	f, err := os.Open(file)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	buffer := make([]byte, 512)
+	n, err := io.ReadFull(f, buffer)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return false
+	}
+	buffer = buffer[:n]
+
+	// A rune may be cut off at the end of the buffer
+	for idx := 0; idx < utf8.UTFMax-1 && n == 512 && !utf8.Valid(buffer); idx++ {
+		buffer = buffer[:len(buffer)-1]
+	}
+	if !utf8.Valid(buffer) {
+		return false
+	}
+
+	for _, b := range buffer {
+		if b < 0x20 && b != '\n' && b != '\r' && b != '\t' && b != '\f' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Parents returns the types the type is a subclass of, directly or indirectly, from the closest to the most general.
+// All text types are text/plain, all others application/octet-stream.
+func (db *MimeDatabase) Parents(mimeType string) []string {
+	var result []string
+	seen := map[string]bool{mimeType: true}
+
+	queue := []string{db.Unalias(mimeType)}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		parents := db.parents[current]
+		if len(parents) == 0 && strings.HasPrefix(current, "text/") && current != "text/plain" {
+			parents = []string{"text/plain"}
+		}
+		if len(parents) == 0 && current != "application/octet-stream" && !strings.HasPrefix(current, "inode/") {
+			parents = []string{"application/octet-stream"}
+		}
+
+		for _, parent := range parents {
+			if parent = db.Unalias(parent); !seen[parent] {
+				seen[parent] = true
+				result = append(result, parent)
+				queue = append(queue, parent)
+			}
+		}
+	}
+
+	return result
+}
+
+// IconNames returns the names of the icons of the type, from the most to the least specific: the icon of the
+// database, the type with - for /, the generic icon and the generic icon of the media type
+func (db *MimeDatabase) IconNames(mimeType string) []string {
+	mimeType = db.Unalias(mimeType)
+
+	var result []string
+	if icon, found := db.icons[mimeType]; found {
+		result = append(result, icon)
+	}
+	result = append(result, strings.ReplaceAll(mimeType, "/", "-"))
+
+	if icon, found := db.genericIcons[mimeType]; found {
+		result = append(result, icon)
+	}
+	if media, _, found := strings.Cut(mimeType, "/"); found {
+		result = append(result, media+"-x-generic")
+	}
+
+	return result
+}
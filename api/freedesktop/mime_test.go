@@ -0,0 +1,84 @@
+package freedesktop
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mimeDatabase writes a database to the data home and a system data dir, the data home wins
+func testMimeDatabase(t *testing.T) (*MimeDatabase, string) {
+	root := t.TempDir()
+
+	writeFile(t, filepath.Join(root, "home", "mime", "icons"), "text/x-go:go-source\n")
+	writeFile(t, filepath.Join(root, "system", "mime", "globs2"), `# Comment
+50:text/plain:*.txt
+50:text/x-go:*.go
+50:application/x-compressed-tar:*.tar.gz
+40:application/gzip:*.gz
+50:text/x-makefile:makefile
+50:text/x-readme:README*:cs
+`)
+	writeFile(t, filepath.Join(root, "system", "mime", "icons"), "text/x-go:ignored\n")
+	writeFile(t, filepath.Join(root, "system", "mime", "aliases"), "application/x-gzip application/gzip\n")
+	writeFile(t, filepath.Join(root, "system", "mime", "subclasses"), "application/x-compressed-tar application/gzip\ntext/x-go text/plain\n")
+	writeFile(t, filepath.Join(root, "system", "mime", "generic-icons"), "application/x-compressed-tar:package-x-generic\n")
+
+	return ReadMimeDatabase([]string{filepath.Join(root, "home"), filepath.Join(root, "system")}), root
+}
+
+func TestMimeDatabase_TypeByName(t *testing.T) {
+	db, _ := testMimeDatabase(t)
+
+	for name, expected := range map[string]string{
+		"notes.TXT":        "text/plain",
+		"/src/main.go":     "text/x-go",
+		"backup.tar.gz":    "application/x-compressed-tar",
+		"data.gz":          "application/gzip",
+		"Makefile":         "text/x-makefile",
+		"README.md":        "text/x-readme",
+		"readme-first.txt": "text/plain",
+	} {
+		mimeType, found := db.TypeByName(name)
+		assert.True(t, found, name)
+		assert.Equal(t, expected, mimeType, name)
+	}
+
+	_, found := db.TypeByName("picture.png")
+	assert.False(t, found)
+}
+
+func TestMimeDatabase_TypeOfFile(t *testing.T) {
+	db, root := testMimeDatabase(t)
+
+	writeFile(t, filepath.Join(root, "text"), "plain text\n")
+	require.NoError(t, os.WriteFile(filepath.Join(root, "binary"), []byte{0x7f, 'E', 'L', 'F', 0, 1}, 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "program"), []byte{0x7f, 'E', 'L', 'F', 0, 1}, 0755))
+
+	assert.Equal(t, "inode/directory", db.TypeOfFile(root))
+	assert.Equal(t, "text/plain", db.TypeOfFile(filepath.Join(root, "text")))
+	assert.Equal(t, "application/octet-stream", db.TypeOfFile(filepath.Join(root, "binary")))
+	assert.Equal(t, "application/x-executable", db.TypeOfFile(filepath.Join(root, "program")))
+	assert.Equal(t, "text/x-go", db.TypeOfFile(filepath.Join(root, "missing.go")), "by name if it does not exist")
+}
+
+func TestMimeDatabase_IconNames(t *testing.T) {
+	db, _ := testMimeDatabase(t)
+
+	assert.Equal(t, []string{"go-source", "text-x-go", "text-x-generic"}, db.IconNames("text/x-go"))
+	assert.Equal(t, []string{"application-gzip", "application-x-generic"}, db.IconNames("application/x-gzip"))
+	assert.Equal(t, []string{"application-x-compressed-tar", "package-x-generic", "application-x-generic"},
+		db.IconNames("application/x-compressed-tar"))
+}
+
+func TestMimeDatabase_Parents(t *testing.T) {
+	db, _ := testMimeDatabase(t)
+
+	assert.Equal(t, []string{"application/gzip", "application/octet-stream"}, db.Parents("application/x-compressed-tar"))
+	assert.Equal(t, []string{"text/plain", "application/octet-stream"}, db.Parents("text/x-go"))
+	assert.Equal(t, []string{"text/plain", "application/octet-stream"}, db.Parents("text/x-unknown"))
+	assert.Empty(t, db.Parents("inode/directory"))
+}
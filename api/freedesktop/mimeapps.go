@@ -0,0 +1,104 @@
+package freedesktop
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// mimeAppsLists returns the mimeapps.list files in the order of the Association between MIME types and applications
+// specification, the lists of the current desktop come before the others in each directory
+func mimeAppsLists() []string {
+	var desktops []string
+	for _, desktop := range strings.Split(os.Getenv("XDG_CURRENT_DESKTOP"), ":") {
+		if len(desktop) > 0 {
+			desktops = append(desktops, strings.ToLower(desktop))
+		}
+	}
+
+	dirs := ConfigDirs()
+	dirs = append(dirs, ApplicationDirs()...)
+
+	var result []string
+	for _, dir := range dirs {
+		for _, desktop := range desktops {
+			result = append(result, filepath.Join(dir, desktop+"-mimeapps.list"))
+		}
+		result = append(result, filepath.Join(dir, "mimeapps.list"))
+	}
+
+	return result
+}
+
+// Applications returns the desktop file IDs of the installed applications that open files of the type, from the most
+// to the least preferred: the default applications, the added associations and the applications that declare the type
+// in their desktop entry, for the type and then for the types it is a subclass of. Removed associations are left out.
+func (db *MimeDatabase) Applications(mimeType string) []string {
+	var lists []KeyFile
+	for _, file := range mimeAppsLists() {
+		if list, err := ReadKeyFile(file); err == nil {
+			lists = append(lists, list)
+		}
+	}
+
+	var caches []KeyFile
+	for _, dir := range ApplicationDirs() {
+		if cache, err := ReadKeyFile(filepath.Join(dir, "mimeinfo.cache")); err == nil {
+			caches = append(caches, cache)
+		}
+	}
+
+	types := []string{db.Unalias(mimeType)}
+	for _, parent := range db.Parents(mimeType) {
+		// Every file is application/octet-stream, that does not make an application suitable for it
+		if parent != "application/octet-stream" {
+			types = append(types, parent)
+		}
+	}
+
+	var result []string
+	seen := make(map[string]bool)
+	add := func(id string) {
+		if seen[id] {
+			return
+		}
+		seen[id] = true
+
+		if file, found := FindEntry(id); found {
+			if e, err := ReadEntry(file, id, nil); err == nil && !e.Hidden && e.Type == "Application" {
+				result = append(result, id)
+			}
+		}
+	}
+
+	for _, each := range types {
+		for _, list := range lists {
+			for _, id := range List(list["Default Applications"][each]) {
+				add(id)
+			}
+		}
+
+		// A removal hides the associations of the same and less important lists
+		removed := make(map[string]bool)
+		for _, list := range lists {
+			for _, id := range List(list["Removed Associations"][each]) {
+				removed[id] = true
+			}
+			for _, id := range List(list["Added Associations"][each]) {
+				if !removed[id] {
+					add(id)
+				}
+			}
+		}
+
+		for _, cache := range caches {
+			for _, id := range List(cache["MIME Cache"][each]) {
+				if !removed[id] {
+					add(id)
+				}
+			}
+		}
+	}
+
+	return result
+}
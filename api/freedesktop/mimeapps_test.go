@@ -0,0 +1,106 @@
+package freedesktop
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// useApplications installs applications in the data home and a system data dir, and associates them with types in
+// the mimeapps.list files of the config home, the desktop and the system
+func useApplications(t *testing.T) (*MimeDatabase, string) {
+	db, root := testMimeDatabase(t)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(root, "config"))
+	t.Setenv("XDG_CONFIG_DIRS", filepath.Join(root, "etc"))
+	t.Setenv("XDG_DATA_HOME", filepath.Join(root, "home"))
+	t.Setenv("XDG_DATA_DIRS", filepath.Join(root, "system"))
+	t.Setenv("XDG_CURRENT_DESKTOP", "Test")
+
+	application := func(dir string, id string, exec string) {
+		writeFile(t, filepath.Join(root, dir, "applications", id), "[Desktop Entry]\nType=Application\nName="+id+"\nExec="+exec+"\n")
+	}
+	application("home", "editor.desktop", "editor %F")
+	application("system", "viewer.desktop", "viewer %U")
+	application("system", "ide.desktop", "ide %f")
+	application("system", "vendor/archiver.desktop", "archiver %f")
+	application("system", "copier.desktop", "cp %f "+filepath.Join(root, "copied"))
+	writeFile(t, filepath.Join(root, "system", "applications", "hidden.desktop"), "[Desktop Entry]\nType=Application\nHidden=true\n")
+
+	writeFile(t, filepath.Join(root, "config", "test-mimeapps.list"), `[Default Applications]
+text/x-go=missing.desktop;ide.desktop;
+`)
+	writeFile(t, filepath.Join(root, "config", "mimeapps.list"), `[Default Applications]
+text/x-go=editor.desktop
+
+[Removed Associations]
+text/plain=viewer.desktop
+`)
+	writeFile(t, filepath.Join(root, "etc", "mimeapps.list"), `[Added Associations]
+text/plain=hidden.desktop;viewer.desktop;editor.desktop;
+application/gzip=vendor-archiver.desktop
+text/x-makefile=copier.desktop
+`)
+	writeFile(t, filepath.Join(root, "system", "applications", "mimeinfo.cache"), `[MIME Cache]
+text/plain=viewer.desktop;ide.desktop;
+`)
+
+	return db, root
+}
+
+func TestMimeDatabase_Applications(t *testing.T) {
+	db, _ := useApplications(t)
+
+	assert.Equal(t, []string{"ide.desktop", "editor.desktop"}, db.Applications("text/x-go"),
+		"the defaults of the desktop come first, the applications of text/plain are not in the way of the defaults")
+	assert.Equal(t, []string{"editor.desktop", "ide.desktop"}, db.Applications("text/plain"),
+		"viewer.desktop is removed and hidden.desktop deleted")
+	assert.Equal(t, []string{"vendor-archiver.desktop"}, db.Applications("application/x-compressed-tar"),
+		"the applications of the parent type")
+	assert.Empty(t, db.Applications("image/png"))
+}
+
+func TestFindEntry(t *testing.T) {
+	_, root := useApplications(t)
+
+	file, found := FindEntry("vendor-archiver.desktop")
+	require.True(t, found)
+	assert.Equal(t, filepath.Join(root, "system", "applications", "vendor", "archiver.desktop"), file)
+
+	file, found = FindEntry("editor.desktop")
+	require.True(t, found)
+	assert.Equal(t, filepath.Join(root, "home", "applications", "editor.desktop"), file)
+
+	_, found = FindEntry("missing.desktop")
+	assert.False(t, found)
+}
+
+func TestMimeDatabase_Open(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("desktop entries are not launched on Windows")
+	}
+
+	db, root := useApplications(t)
+
+	makefile := filepath.Join(root, "makefile")
+	writeFile(t, makefile, "all:\n")
+	require.NoError(t, db.Open(makefile))
+	assert.Eventually(t, func() bool {
+		_, err := os.Stat(filepath.Join(root, "copied"))
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond, "the application copies the file")
+
+	err := db.Open(filepath.Join(root, "missing.txt"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	// Without an application for the type and without xdg-open
+	t.Setenv("PATH", t.TempDir())
+	picture := filepath.Join(root, "picture.png")
+	writeFile(t, picture, "\x89PNG\x00")
+	err = db.Open(picture)
+	assert.EqualError(t, err, "no application to open "+picture+" (application/octet-stream) and xdg-open is not installed")
+}
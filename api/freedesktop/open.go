@@ -0,0 +1,98 @@
+package freedesktop
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// openerTimeout is how long Open waits for xdg-open to fail, it may keep running for as long as the application runs
+const openerTimeout = 2 * time.Second
+
+// Open opens the file with the preferred application of its MIME type in the database of the data dirs
+func Open(file string) error {
+	return Mime().Open(file)
+}
+
+// Open opens the file with the preferred application of its MIME type, or with xdg-open if no application is
+// associated with the type. The application keeps running when the launcher exits.
+func (db *MimeDatabase) Open(file string) error {
+	if _, err := os.Stat(file); err != nil {
+		return err
+	}
+
+	mimeType := db.TypeOfFile(file)
+
+	for _, id := range db.Applications(mimeType) {
+		entryFile, found := FindEntry(id)
+		if !found {
+			continue
+		}
+
+		e, err := ReadEntry(entryFile, id, Locales())
+		if err != nil {
+			return err
+		}
+
+		args, err := Command(e, "", []string{file})
+		if err != nil {
+			return err
+		}
+
+		if err := Launch(args, e.Path); err != nil {
+			return fmt.Errorf("failed to open %s with %s: %w", file, id, err)
+		}
+		return nil
+	}
+
+	return openWithXdgOpen(file, mimeType)
+}
+
+// openWithXdgOpen opens the file with xdg-open, which knows how the desktop environment opens files. It reports the
+// errors of xdg-open that happen within openerTimeout.
+func openWithXdgOpen(file string, mimeType string) error {
+	if _, err := exec.LookPath("xdg-open"); err != nil {
+		return fmt.Errorf("no application to open %s (%s) and xdg-open is not installed", file, mimeType)
+	}
+
+	cmd := exec.Command("xdg-open", file)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	detach(cmd)
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+
+	select {
+	case err := <-done:
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			if message := strings.TrimSpace(stderr.String()); len(message) > 0 {
+				return fmt.Errorf("xdg-open %s: %s", file, lastLine(message))
+			}
+			return fmt.Errorf("xdg-open %s: %w", file, err)
+		}
+		return err
+	case <-time.After(openerTimeout):
+		// Still running, the application was started. The goroutine reaps it when it exits.
+		return nil
+	}
+}
+
+func lastLine(s string) string {
+	if idx := strings.LastIndexByte(s, '\n'); idx >= 0 {
+		return s[idx+1:]
+	}
+
+	return s
+}
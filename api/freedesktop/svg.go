@@ -0,0 +1,38 @@
+package freedesktop
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"math"
+
+	"github.com/srwiley/oksvg"
+	"github.com/srwiley/rasterx"
+)
+
+// RasterizeSVG renders the SVG document into an image of size×size pixels. The viewBox is scaled to the image keeping
+// its aspect ratio. Elements the rasterizer doesn't support, like text and filters, are skipped.
+func RasterizeSVG(r io.Reader, size int) (image.Image, error) {
+	icon, err := oksvg.ReadIconStream(r, oksvg.IgnoreErrorMode)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SVG: %w", err)
+	}
+
+	// Documents without a viewBox use their width and height, without either there is nothing to scale
+	box := icon.ViewBox
+	if box.W <= 0 || box.H <= 0 {
+		return nil, errors.New("invalid SVG: no viewBox or size")
+	}
+
+	// The viewBox is scaled to the image, keeping the aspect ratio and centered
+	scale := math.Min(float64(size)/box.W, float64(size)/box.H)
+	width, height := box.W*scale, box.H*scale
+	icon.SetTarget((float64(size)-width)/2, (float64(size)-height)/2, width, height)
+
+	im := image.NewRGBA(image.Rect(0, 0, size, size))
+	scanner := rasterx.NewScannerGV(size, size, im, im.Bounds())
+	icon.Draw(rasterx.NewDasher(size, size, scanner), 1)
+
+	return im, nil
+}
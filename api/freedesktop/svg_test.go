@@ -0,0 +1,83 @@
+package freedesktop
+
+import (
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func render(t *testing.T, svg string, size int) *image.RGBA {
+	im, err := RasterizeSVG(strings.NewReader(svg), size)
+	require.NoError(t, err)
+
+	return im.(*image.RGBA)
+}
+
+// alphaAt returns the opacity of the pixel, which is the coverage of the shapes for opaque fills
+func alphaAt(im *image.RGBA, x, y int) uint8 {
+	return im.RGBAAt(x, y).A
+}
+
+func TestRasterizeSVG_Shapes(t *testing.T) {
+	// The viewBox is scaled to the image
+	im := render(t, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 32 32">
+  <rect x="0" y="0" width="16" height="16" fill="#ff0000"/>
+  <circle cx="24" cy="24" r="8" style="fill:rgb(0,0,255)"/>
+  <path d="M16 0h16v16z" fill="lime" fill-opacity="0.5"/>
+  <rect x="0" y="16" width="16" height="16" fill="none"/>
+</svg>`, 16)
+
+	assert.Equal(t, color.RGBA{255, 0, 0, 255}, im.RGBAAt(4, 4))
+	assert.Equal(t, color.RGBA{0, 0, 255, 255}, im.RGBAAt(12, 12))
+	assert.Equal(t, uint8(0), alphaAt(im, 15, 15), "outside of the circle")
+	assert.Equal(t, uint8(0), alphaAt(im, 9, 6), "below the diagonal of the triangle")
+	assert.InDelta(t, 128, int(alphaAt(im, 14, 1)), 1, "half opaque")
+	assert.Equal(t, uint8(0), alphaAt(im, 4, 12), "not filled")
+}
+
+func TestRasterizeSVG_TransformsAndGroups(t *testing.T) {
+	// Without a viewBox the width and height are scaled to the image
+	im := render(t, `<svg width="16" height="16">
+  <defs>
+    <linearGradient id="gradient"><stop offset="0" stop-color="#000"/><stop offset="1" stop-color="#fff"/></linearGradient>
+    <rect id="square" width="4" height="4" fill="#00ff00"/>
+  </defs>
+  <g transform="translate(8 0)">
+    <rect width="8" height="4" fill="url(#gradient)"/>
+  </g>
+  <use href="#square" x="0" y="8"/>
+  <g opacity="0.5"><rect x="12" y="12" width="4" height="4"/></g>
+</svg>`, 32)
+
+	assert.Less(t, im.RGBAAt(18, 2).R, im.RGBAAt(30, 2).R, "the gradient gets lighter")
+	assert.Equal(t, uint8(0), alphaAt(im, 2, 2), "moved")
+	assert.Equal(t, color.RGBA{0, 255, 0, 255}, im.RGBAAt(2, 18))
+	assert.InDelta(t, 128, int(alphaAt(im, 28, 28)), 1, "half opaque")
+}
+
+func TestRasterizeSVG_AspectRatioAndStroke(t *testing.T) {
+	// A wide viewBox is centered vertically
+	im := render(t, `<svg viewBox="0 0 32 16"><rect width="32" height="16"/></svg>`, 16)
+	assert.Equal(t, uint8(0), alphaAt(im, 8, 1))
+	assert.Equal(t, uint8(255), alphaAt(im, 8, 8))
+	assert.Equal(t, uint8(0), alphaAt(im, 8, 14))
+
+	// Relative commands, arcs and a stroke without fill
+	im = render(t, `<svg viewBox="0 0 16 16">
+  <path d="m2 8a6 6 0 1 1 12 0" fill="none" stroke="#000" stroke-width="2"/>
+</svg>`, 16)
+	assert.Equal(t, uint8(0), alphaAt(im, 8, 8), "the inside of the arc is not filled")
+	assert.Greater(t, alphaAt(im, 8, 2), uint8(200), "on the stroke")
+}
+
+func TestRasterizeSVG_Invalid(t *testing.T) {
+	_, err := RasterizeSVG(strings.NewReader(`<html></html>`), 16)
+	assert.EqualError(t, err, "invalid SVG: no viewBox or size")
+
+	_, err = RasterizeSVG(strings.NewReader(`<svg viewBox="0 0 16"/>`), 16)
+	assert.Error(t, err)
+}
@@ -0,0 +1,300 @@
+package freedesktop
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// iconExtensions are the formats of icons that are supported, in the order of the Icon Theme Specification
+var iconExtensions = []string{".png", ".svg"}
+
+// Theme is an icon theme, see the Icon Theme Specification
+type Theme struct {
+	Name     string
+	Inherits []string // The themes that are searched for icons this theme does not have
+	dirs     []themeDir
+}
+
+// themeDir is a directory of icons of a theme, e.g. 16x16/apps
+type themeDir struct {
+	name      string
+	size      int
+	scale     int
+	minSize   int
+	maxSize   int
+	threshold int
+	kind      string            // Fixed, Scalable or Threshold
+	icons     map[string]string // The files of the icons by their name, the directory may exist in several base dirs
+}
+
+// IconDirs returns the base directories of icon themes, from the most to the least important
+func IconDirs() []string {
+	var result []string
+	if home, err := os.UserHomeDir(); err == nil {
+		result = append(result, filepath.Join(home, ".icons"))
+	}
+	for _, dir := range DataDirs() {
+		result = append(result, filepath.Join(dir, "icons"))
+	}
+
+	return append(result, "/usr/share/pixmaps")
+}
+
+var themes = struct {
+	mutex   sync.Mutex
+	loaded  map[string]*Theme
+	current string
+}{loaded: make(map[string]*Theme)}
+
+// LoadTheme returns the icon theme with the name, themes are only read once. A theme that is not installed has no
+// icons.
+func LoadTheme(name string) *Theme {
+	themes.mutex.Lock()
+	defer themes.mutex.Unlock()
+
+	if theme, found := themes.loaded[name]; found {
+		return theme
+	}
+
+	theme := readTheme(name, IconDirs())
+	themes.loaded[name] = theme
+	return theme
+}
+
+// ClearThemes forgets the themes that were loaded and the current theme, e.g. after the user changed the theme
+func ClearThemes() {
+	themes.mutex.Lock()
+	themes.loaded = make(map[string]*Theme)
+	themes.current = ""
+	themes.mutex.Unlock()
+}
+
+func readTheme(name string, bases []string) *Theme {
+	theme := &Theme{Name: name}
+
+	var index map[string]map[string]string
+	for _, base := range bases {
+		if file, err := ReadKeyFile(filepath.Join(base, name, "index.theme")); err == nil {
+			index = file
+			break
+		}
+	}
+	if index == nil {
+		return theme
+	}
+
+	main := index["Icon Theme"]
+	theme.Inherits = List(main["Inherits"])
+
+	for _, subdir := range append(List(main["Directories"]), List(main["ScaledDirectories"])...) {
+		keys, found := index[subdir]
+		if !found {
+			continue
+		}
+
+		dir := themeDir{
+			name:      subdir,
+			size:      atoi(keys["Size"], 0),
+			scale:     atoi(keys["Scale"], 1),
+			threshold: atoi(keys["Threshold"], 2),
+			kind:      keys["Type"],
+			icons:     make(map[string]string),
+		}
+		dir.minSize = atoi(keys["MinSize"], dir.size)
+		dir.maxSize = atoi(keys["MaxSize"], dir.size)
+		if len(dir.kind) == 0 {
+			dir.kind = "Threshold"
+		}
+
+		for _, base := range bases {
+			addIcons(dir.icons, filepath.Join(base, name, subdir))
+		}
+
+		theme.dirs = append(theme.dirs, dir)
+	}
+
+	return theme
+}
+
+// addIcons adds the icons in the directory that are not in the map yet, a PNG wins over an SVG with the same name
+func addIcons(icons map[string]string, dir string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+
+	for _, extension := range iconExtensions {
+		for _, entry := range entries {
+			if filepath.Ext(entry.Name()) != extension {
+				continue
+			}
+
+			name := strings.TrimSuffix(entry.Name(), extension)
+			if _, found := icons[name]; !found {
+				icons[name] = filepath.Join(dir, entry.Name())
+			}
+		}
+	}
+}
+
+func atoi(value string, fallback int) int {
+	if n, err := strconv.Atoi(value); err == nil {
+		return n
+	}
+
+	return fallback
+}
+
+func (d themeDir) matchesSize(size int, scale int) bool {
+	if d.scale != scale {
+		return false
+	}
+
+	switch d.kind {
+	case "Fixed":
+		return d.size == size
+	case "Scalable":
+		return d.minSize <= size && size <= d.maxSize
+	default:
+		return d.size-d.threshold <= size && size <= d.size+d.threshold
+	}
+}
+
+func (d themeDir) sizeDistance(size int, scale int) int {
+	pixels := size * scale
+
+	var min, max int
+	switch d.kind {
+	case "Fixed":
+		min, max = d.size*d.scale, d.size*d.scale
+	case "Scalable":
+		min, max = d.minSize*d.scale, d.maxSize*d.scale
+	default:
+		min, max = (d.size-d.threshold)*d.scale, (d.size+d.threshold)*d.scale
+	}
+
+	switch {
+	case pixels < min:
+		return min - pixels
+	case pixels > max:
+		return pixels - max
+	default:
+		return 0
+	}
+}
+
+// lookup returns the file of the icon in a directory that matches the size, or else in the directory that is closest
+// to the size
+func (t *Theme) lookup(icon string, size int, scale int) (string, bool) {
+	for _, dir := range t.dirs {
+		if file, found := dir.icons[icon]; found && dir.matchesSize(size, scale) {
+			return file, true
+		}
+	}
+
+	closest, distance := "", -1
+	for _, dir := range t.dirs {
+		if file, found := dir.icons[icon]; found {
+			if d := dir.sizeDistance(size, scale); distance < 0 || d < distance {
+				closest, distance = file, d
+			}
+		}
+	}
+
+	return closest, distance >= 0
+}
+
+// find looks the icon up in the theme and the themes it inherits from
+func (t *Theme) find(icon string, size int, scale int, visited map[string]bool) (string, bool) {
+	if visited[t.Name] {
+		return "", false
+	}
+	visited[t.Name] = true
+
+	if file, found := t.lookup(icon, size, scale); found {
+		return file, true
+	}
+
+	for _, parent := range t.Inherits {
+		if file, found := LoadTheme(parent).find(icon, size, scale, visited); found {
+			return file, true
+		}
+	}
+
+	return "", false
+}
+
+// FindIcon returns the file of the icon with the name and size in pixels, it looks in the theme and the themes it
+// inherits from, then in hicolor and finally in the base directories themselves. An absolute path is returned as is.
+func FindIcon(theme string, icon string, size int) (string, bool) {
+	if filepath.IsAbs(icon) {
+		_, err := os.Stat(icon)
+		return icon, err == nil
+	}
+
+	// Some desktop entries name the file of the icon instead of the icon
+	for _, extension := range append(iconExtensions, ".xpm") {
+		icon = strings.TrimSuffix(icon, extension)
+	}
+
+	visited := make(map[string]bool)
+	for _, name := range []string{theme, "hicolor"} {
+		if file, found := LoadTheme(name).find(icon, size, 1, visited); found {
+			return file, true
+		}
+	}
+
+	for _, base := range IconDirs() {
+		for _, extension := range iconExtensions {
+			file := filepath.Join(base, icon+extension)
+			if _, err := os.Stat(file); err == nil {
+				return file, true
+			}
+		}
+	}
+
+	return "", false
+}
+
+// CurrentTheme returns the name of the icon theme of the desktop: the theme of the GTK or KDE settings, or of GNOME.
+// It is hicolor if the user selected none. The theme is only looked up once.
+func CurrentTheme() string {
+	themes.mutex.Lock()
+	defer themes.mutex.Unlock()
+
+	if len(themes.current) == 0 {
+		themes.current = currentTheme()
+	}
+
+	return themes.current
+}
+
+func currentTheme() string {
+	for _, dir := range ConfigDirs() {
+		for _, file := range []string{filepath.Join("gtk-3.0", "settings.ini"), filepath.Join("gtk-4.0", "settings.ini")} {
+			if settings, err := ReadKeyFile(filepath.Join(dir, file)); err == nil {
+				if name := settings["Settings"]["gtk-icon-theme-name"]; len(name) > 0 {
+					return name
+				}
+			}
+		}
+
+		if settings, err := ReadKeyFile(filepath.Join(dir, "kdeglobals")); err == nil {
+			if name := settings["Icons"]["Theme"]; len(name) > 0 {
+				return name
+			}
+		}
+	}
+
+	if output, err := exec.Command("gsettings", "get", "org.gnome.desktop.interface", "icon-theme").Output(); err == nil {
+		if name := strings.Trim(strings.TrimSpace(string(output)), "'"); len(name) > 0 {
+			return name
+		}
+	}
+
+	return "hicolor"
+}
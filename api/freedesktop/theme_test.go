@@ -0,0 +1,175 @@
+package freedesktop
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, file string, content string) {
+	require.NoError(t, os.MkdirAll(filepath.Dir(file), 0755))
+	require.NoError(t, os.WriteFile(file, []byte(content), 0644))
+}
+
+// writeIcon writes a PNG of the size in a single color, so the tests can tell the icons apart
+func writeIcon(t *testing.T, file string, size int, c color.Color) {
+	im := image.NewRGBA(image.Rect(0, 0, size, size))
+	for idx := 0; idx < size*size; idx++ {
+		im.Set(idx%size, idx/size, c)
+	}
+
+	require.NoError(t, os.MkdirAll(filepath.Dir(file), 0755))
+	f, err := os.Create(file)
+	require.NoError(t, err)
+	defer f.Close()
+	require.NoError(t, png.Encode(f, im))
+}
+
+// useThemes creates a child theme that inherits from a parent theme, and hicolor in the data home. The home directory
+// is empty so ~/.icons does not interfere.
+func useThemes(t *testing.T) string {
+	root := t.TempDir()
+	t.Setenv("HOME", filepath.Join(root, "home"))
+	t.Setenv("XDG_DATA_HOME", filepath.Join(root, "data"))
+	t.Setenv("XDG_DATA_DIRS", filepath.Join(root, "system"))
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(root, "config"))
+	t.Setenv("XDG_CONFIG_DIRS", filepath.Join(root, "etc"))
+	ClearThemes()
+	t.Cleanup(ClearThemes)
+
+	icons := filepath.Join(root, "data", "icons")
+	writeFile(t, filepath.Join(icons, "Child", "index.theme"), `[Icon Theme]
+Name=Child
+Inherits=Parent
+Directories=16x16/apps,48x48/apps
+
+[16x16/apps]
+Size=16
+Type=Fixed
+
+[48x48/apps]
+Size=48
+Type=Fixed
+`)
+	writeFile(t, filepath.Join(icons, "Parent", "index.theme"), `[Icon Theme]
+Name=Parent
+Inherits=Child
+Directories=scalable/apps
+
+[scalable/apps]
+Size=48
+MinSize=8
+MaxSize=512
+Type=Scalable
+`)
+	writeFile(t, filepath.Join(icons, "hicolor", "index.theme"), `[Icon Theme]
+Name=Hicolor
+Directories=32x32/apps
+
+[32x32/apps]
+Size=32
+`)
+
+	writeIcon(t, filepath.Join(icons, "Child", "16x16", "apps", "small.png"), 16, color.White)
+	writeIcon(t, filepath.Join(icons, "Child", "48x48", "apps", "small.png"), 48, color.White)
+	writeIcon(t, filepath.Join(icons, "Child", "48x48", "apps", "large.png"), 48, color.White)
+	writeFile(t, filepath.Join(icons, "Parent", "scalable", "apps", "vector.svg"), `<svg viewBox="0 0 10 10"/>`)
+	writeFile(t, filepath.Join(icons, "Parent", "scalable", "apps", "small.svg"), `<svg viewBox="0 0 10 10"/>`)
+	writeIcon(t, filepath.Join(icons, "hicolor", "32x32", "apps", "fallback.png"), 32, color.White)
+	// The theme may be spread over several base directories
+	writeIcon(t, filepath.Join(root, "system", "icons", "Child", "16x16", "apps", "system.png"), 16, color.White)
+	writeIcon(t, filepath.Join(root, "system", "icons", "pixmap.png"), 16, color.White)
+
+	return root
+}
+
+func TestFindIcon(t *testing.T) {
+	root := useThemes(t)
+	relative := func(file string) string {
+		result, err := filepath.Rel(root, file)
+		require.NoError(t, err)
+		return filepath.ToSlash(result)
+	}
+
+	for _, tc := range []struct {
+		icon     string
+		size     int
+		expected string
+	}{
+		{"small", 16, "data/icons/Child/16x16/apps/small.png"},
+		{"small", 48, "data/icons/Child/48x48/apps/small.png"},
+		{"small.png", 16, "data/icons/Child/16x16/apps/small.png"},
+		{"large", 16, "data/icons/Child/48x48/apps/large.png"},
+		{"system", 16, "system/icons/Child/16x16/apps/system.png"},
+		{"vector", 16, "data/icons/Parent/scalable/apps/vector.svg"},
+		{"fallback", 16, "data/icons/hicolor/32x32/apps/fallback.png"},
+		{"pixmap", 16, "system/icons/pixmap.png"},
+	} {
+		file, found := FindIcon("Child", tc.icon, tc.size)
+		require.True(t, found, tc.icon)
+		assert.Equal(t, tc.expected, relative(file), "%s at %d", tc.icon, tc.size)
+	}
+
+	_, found := FindIcon("Child", "missing", 16)
+	assert.False(t, found, "the themes inherit from each other")
+
+	_, found = FindIcon("Unknown", "fallback", 16)
+	assert.True(t, found, "hicolor is searched for themes that are not installed")
+}
+
+func TestCurrentTheme(t *testing.T) {
+	root := useThemes(t)
+	writeFile(t, filepath.Join(root, "etc", "gtk-3.0", "settings.ini"), "[Settings]\ngtk-icon-theme-name=Child\n")
+
+	assert.Equal(t, "Child", CurrentTheme())
+}
+
+func TestFileIcon(t *testing.T) {
+	root := useThemes(t)
+	writeFile(t, filepath.Join(root, "etc", "kdeglobals"), "[Icons]\nTheme=Child\n")
+	writeFile(t, filepath.Join(root, "app.desktop"), "[Desktop Entry]\nType=Application\nName=App\nIcon=large\n")
+	writeFile(t, filepath.Join(root, "unknown.desktop"), "[Desktop Entry]\nType=Application\nName=App\nIcon=missing\n")
+
+	cache := filepath.Join(root, "cache")
+	SetIconCacheDir(cache)
+	t.Cleanup(func() { SetIconCacheDir("") })
+
+	im, err := FileIcon(filepath.Join(root, "app.desktop"), 24)
+	require.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 24, 24), im.Bounds(), "scaled to the size")
+
+	cached, err := os.ReadDir(cache)
+	require.NoError(t, err)
+	assert.Len(t, cached, 1)
+
+	_, err = FileIcon(filepath.Join(root, "unknown.desktop"), 16)
+	assert.EqualError(t, err, "no icon missing, application-x-executable in theme Child")
+}
+
+func TestParseKeyFile(t *testing.T) {
+	file, err := ParseKeyFile(strings.NewReader(`# Comment
+[Group]
+Key = value
+Key=ignored
+Name[de]=Wert
+
+[Other]
+List=a;b,c;;
+`))
+	require.NoError(t, err)
+	assert.Equal(t, KeyFile{
+		"Group": {"Key": "value", "Name[de]": "Wert"},
+		"Other": {"List": "a;b,c;;"},
+	}, file)
+	assert.Equal(t, []string{"a", "b", "c"}, List(file["Other"]["List"]))
+
+	_, err = ParseKeyFile(strings.NewReader("Key=value\n"))
+	assert.EqualError(t, err, "line 1: key outside of a group")
+}
@@ -2,8 +2,8 @@ package api
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
-	"log"
 	"strings"
 )
 
@@ -16,7 +16,87 @@ const (
 
 type Hotkey struct {
 	Modifiers int // Mask of modifiers
-	KeyCode   int // Key code, e.g. 'A'
+	KeyCode   int // Windows virtual-key code, e.g. 'A' or 0x70 for F1
+}
+
+// namedKeys are the virtual-key codes of the keys that have a name, the first name is the one that is displayed
+var namedKeys = []struct {
+	code  int
+	names []string
+}{
+	{0x20, []string{"Space"}},
+	{0x2D, []string{"Insert", "Ins"}},
+	{0x2E, []string{"Delete", "Del"}},
+	{0x24, []string{"Home"}},
+	{0x23, []string{"End"}},
+	{0x08, []string{"Backspace"}},
+	{0x1B, []string{"Escape", "Esc"}},
+	{0x13, []string{"Pause", "Break"}},
+	{0x09, []string{"Tab"}},
+	{0x0D, []string{"Enter"}},
+	{0x70, []string{"F1"}},
+	{0x71, []string{"F2"}},
+	{0x72, []string{"F3"}},
+	{0x73, []string{"F4"}},
+	{0x74, []string{"F5"}},
+	{0x75, []string{"F6"}},
+	{0x76, []string{"F7"}},
+	{0x77, []string{"F8"}},
+	{0x78, []string{"F9"}},
+	{0x79, []string{"F10"}},
+	{0x7A, []string{"F11"}},
+	{0x7B, []string{"F12"}},
+}
+
+// punctuationKeys are the virtual-key codes of the punctuation keys of a US keyboard
+var punctuationKeys = map[byte]int{
+	';': 0xBA, '=': 0xBB, ',': 0xBC, '-': 0xBD, '.': 0xBE, '/': 0xBF, '`': 0xC0, '[': 0xDB, '\\': 0xDC, ']': 0xDD, '\'': 0xDE,
+}
+
+// keyCode returns the virtual-key code of the lower case name of a key
+func keyCode(name string) (int, bool) {
+	for _, each := range namedKeys {
+		for _, n := range each.names {
+			if strings.EqualFold(n, name) {
+				return each.code, true
+			}
+		}
+	}
+
+	if len(name) != 1 {
+		return 0, false
+	}
+
+	// Letters and digits are their upper case character
+	switch c := name[0]; {
+	case c >= 'a' && c <= 'z':
+		return int(c - 'a' + 'A'), true
+	case c >= '0' && c <= '9':
+		return int(c), true
+	default:
+		code, found := punctuationKeys[c]
+		return code, found
+	}
+}
+
+// keyName returns the name of the key with the virtual-key code, for display
+func keyName(code int) string {
+	for _, each := range namedKeys {
+		if each.code == code {
+			return each.names[0]
+		}
+	}
+
+	if code >= 'A' && code <= 'Z' || code >= '0' && code <= '9' {
+		return string(rune(code))
+	}
+	for c, each := range punctuationKeys {
+		if each == code {
+			return string(c)
+		}
+	}
+
+	return fmt.Sprintf("0x%X", code)
 }
 
 // String returns a human-friendly display name of the hotkey
@@ -36,17 +116,16 @@ func (h *Hotkey) String() string {
 		mod.WriteString("Win+")
 	}
 
-	key := fmt.Sprintf("%c", h.KeyCode)
+	return fmt.Sprintf("Hotkey[%s%s]", mod, keyName(h.KeyCode))
+}
 
-	switch h.KeyCode {
-	case 0x20:
-		key = "Space"
+// ParseHotkey parses a hotkey such as "Ctrl+Shift+Space", modifiers and keys are case-insensitive. It returns an
+// error if a modifier or the key is not supported.
+func ParseHotkey(def string) (Hotkey, error) {
+	if len(strings.TrimSpace(def)) == 0 {
+		return Hotkey{}, errors.New("hotkey is empty")
 	}
 
-	return fmt.Sprintf("Hotkey[%s%s]", mod, key)
-}
-
-func ParseHotkey(def string) Hotkey {
 	a := strings.Split(def, "+")
 	modifier := 0
 	key := -1
@@ -67,71 +146,18 @@ func ParseHotkey(def string) Hotkey {
 			case "win":
 				modifier += ModWin
 			default:
-				log.Fatalf("Hotkey modifier `%s` not supported", m)
+				return Hotkey{}, fmt.Errorf("hotkey modifier %q is not supported, use Ctrl, Shift, Alt or Win", m)
 			}
 
 		} else {
-			switch m {
-			case "space":
-				key = 0x20
-			case "ins":
-				fallthrough
-			case "insert":
-				key = 45
-			case "del":
-				fallthrough
-			case "delete":
-				key = 46
-			case "home":
-				key = 36
-			case "end":
-				key = 35
-			case "backspace":
-				key = 8
-			case "esc":
-				fallthrough
-			case "escape":
-				key = 27
-			case "break":
-				fallthrough
-			case "pause":
-				key = 19
-			case "f1":
-				key = 112
-			case "f2":
-				key = 113
-			case "f3":
-				key = 114
-			case "f4":
-				key = 115
-			case "f5":
-				key = 116
-			case "f6":
-				key = 117
-			case "f7":
-				key = 118
-			case "f8":
-				key = 119
-			case "f9":
-				key = 120
-			case "f10":
-				key = 121
-			case "f11":
-				key = 122
-			case "f12":
-				key = 123
-			case "tab":
-				key = 9
-			case "enter":
-				key = 13
-			default:
-				if len(m) == 1 {
-					key = int(m[0])
-				} else {
-					log.Fatalf("Hotkey `%s` not supported", m)
-				}
+			code, found := keyCode(m)
+			if !found && len(m) == 0 {
+				return Hotkey{}, fmt.Errorf("hotkey %q has no key", def)
+			} else if !found {
+				return Hotkey{}, fmt.Errorf("hotkey key %q is not supported", m)
 			}
+			key = code
 		}
 	}
-	return Hotkey{Modifiers: modifier, KeyCode: key}
+	return Hotkey{Modifiers: modifier, KeyCode: key}, nil
 }
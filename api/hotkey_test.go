@@ -8,19 +8,66 @@ import (
 
 func TestParseHotkey(t *testing.T) {
 	var hotkey Hotkey
+	var err error
 
-	ParseHotkey("Ctrl+Shift+Space")
-	ParseHotkey("Ctrl+Shift +  space ")
+	_, err = ParseHotkey("Ctrl+Shift+Space")
+	assert.NoError(t, err)
+	_, err = ParseHotkey("Ctrl+Shift +  space ")
+	assert.NoError(t, err)
 
-	hotkey = ParseHotkey("ALt+Space ")
+	hotkey, err = ParseHotkey("ALt+Space ")
+	assert.NoError(t, err)
 	assert.Equal(t, ModAlt, hotkey.Modifiers)
 	assert.Equal(t, 32, hotkey.KeyCode)
 
-	hotkey = ParseHotkey("alt+x")
+	hotkey, err = ParseHotkey("alt+x")
+	assert.NoError(t, err)
 	assert.Equal(t, ModAlt, hotkey.Modifiers)
-	assert.Equal(t, 120, hotkey.KeyCode)
+	assert.Equal(t, int('X'), hotkey.KeyCode, "letters are their virtual-key code")
 
-	hotkey = ParseHotkey("ctrl+win+k")
+	hotkey, err = ParseHotkey("ctrl+win+k")
+	assert.NoError(t, err)
 	assert.Equal(t, ModCtrl|ModWin, hotkey.Modifiers)
-	assert.Equal(t, 107, hotkey.KeyCode)
+	assert.Equal(t, int('K'), hotkey.KeyCode)
+
+	// F1 and P are different keys
+	hotkey, err = ParseHotkey("Ctrl+F1")
+	assert.NoError(t, err)
+	assert.Equal(t, 0x70, hotkey.KeyCode)
+	hotkey, err = ParseHotkey("Ctrl+p")
+	assert.NoError(t, err)
+	assert.Equal(t, int('P'), hotkey.KeyCode)
+
+	hotkey, err = ParseHotkey("Shift+/")
+	assert.NoError(t, err)
+	assert.Equal(t, 0xBF, hotkey.KeyCode)
+}
+
+func TestHotkey_String(t *testing.T) {
+	for def, expected := range map[string]string{
+		"alt+space":   "Hotkey[Alt+Space]",
+		"ctrl+win+k":  "Hotkey[Ctrl+Win+K]",
+		"shift+f12":   "Hotkey[Shift+F12]",
+		"ctrl+del":    "Hotkey[Ctrl+Delete]",
+		"ctrl+alt+.":  "Hotkey[Alt+Ctrl+.]",
+		"ctrl+alt+\\": "Hotkey[Alt+Ctrl+\\]",
+	} {
+		hotkey, err := ParseHotkey(def)
+		assert.NoError(t, err, def)
+		assert.Equal(t, expected, hotkey.String(), def)
+	}
+}
+
+func TestParseHotkey_Invalid(t *testing.T) {
+	for def, message := range map[string]string{
+		"Ctl+Space":    `hotkey modifier "ctl" is not supported, use Ctrl, Shift, Alt or Win`,
+		"Ctrl+Spcae":   `hotkey key "spcae" is not supported`,
+		"Ctrl+":        `hotkey "Ctrl+" has no key`,
+		"  ":           `hotkey is empty`,
+		"Alt+Ctrl+F13": `hotkey key "f13" is not supported`,
+		"Alt+é":        `hotkey key "é" is not supported`,
+	} {
+		_, err := ParseHotkey(def)
+		assert.EqualError(t, err, message, def)
+	}
 }
@@ -0,0 +1,92 @@
+//go:build linux || freebsd || openbsd
+
+package api
+
+import (
+	"fmt"
+)
+
+// The X11 modifier masks of X.h
+const (
+	x11ShiftMask   = 1 << 0
+	x11LockMask    = 1 << 1 // Caps Lock
+	x11ControlMask = 1 << 2
+	x11Mod1Mask    = 1 << 3 // Alt
+	x11Mod4Mask    = 1 << 6 // Super, the Windows key
+)
+
+// x11Keysyms are the keysyms of keysymdef.h of the virtual-key codes that are not a letter or digit
+var x11Keysyms = map[int]uint{
+	0x20: 0x0020, // XK_space
+	0x2D: 0xff63, // XK_Insert
+	0x2E: 0xffff, // XK_Delete
+	0x24: 0xff50, // XK_Home
+	0x23: 0xff57, // XK_End
+	0x08: 0xff08, // XK_BackSpace
+	0x1B: 0xff1b, // XK_Escape
+	0x13: 0xff13, // XK_Pause
+	0x09: 0xff09, // XK_Tab
+	0x0D: 0xff0d, // XK_Return
+}
+
+// x11Modifiers returns the X11 modifier mask of the modifiers of a hotkey
+func x11Modifiers(modifiers int) uint {
+	var mask uint
+	if modifiers&ModAlt != 0 {
+		mask |= x11Mod1Mask
+	}
+	if modifiers&ModCtrl != 0 {
+		mask |= x11ControlMask
+	}
+	if modifiers&ModShift != 0 {
+		mask |= x11ShiftMask
+	}
+	if modifiers&ModWin != 0 {
+		mask |= x11Mod4Mask
+	}
+
+	return mask
+}
+
+// x11Keysym returns the X11 keysym of the virtual-key code of a hotkey
+func x11Keysym(keyCode int) (uint, error) {
+	switch {
+	case keyCode >= 'A' && keyCode <= 'Z':
+		// The keysyms of letters are lower case, upper case needs Shift
+		return uint(keyCode - 'A' + 'a'), nil
+	case keyCode >= '0' && keyCode <= '9':
+		return uint(keyCode), nil
+	case keyCode >= 0x70 && keyCode <= 0x7B:
+		return uint(0xffbe + keyCode - 0x70), nil // XK_F1 to XK_F12
+	}
+
+	if keysym, found := x11Keysyms[keyCode]; found {
+		return keysym, nil
+	}
+
+	// The Latin-1 keysyms of punctuation are their character
+	for c, code := range punctuationKeys {
+		if code == keyCode {
+			return uint(c), nil
+		}
+	}
+
+	return 0, fmt.Errorf("key 0x%X has no X11 keysym", keyCode)
+}
+
+// x11GrabMasks returns the modifier mask with all combinations of the lock modifiers. A grab only matches the exact
+// modifiers, so the hotkey is grabbed once for each combination to work when Num Lock or Caps Lock is on.
+func x11GrabMasks(mask uint, numLockMask uint) []uint {
+	result := []uint{mask}
+	for _, lock := range []uint{x11LockMask, numLockMask} {
+		if lock == 0 {
+			continue
+		}
+
+		for _, each := range result {
+			result = append(result, each|lock)
+		}
+	}
+
+	return result
+}
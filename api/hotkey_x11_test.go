@@ -0,0 +1,52 @@
+//go:build linux || freebsd || openbsd
+
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestX11Keysym(t *testing.T) {
+	for def, expected := range map[string]uint{
+		"Alt+Space": 0x0020,
+		"Alt+x":     0x0078,
+		"Alt+7":     0x0037,
+		"Alt+F1":    0xffbe,
+		"Alt+F12":   0xffc9,
+		"Alt+Enter": 0xff0d,
+		"Alt+Del":   0xffff,
+		"Alt+/":     0x002f,
+	} {
+		hotkey, err := ParseHotkey(def)
+		require.NoError(t, err, def)
+
+		keysym, err := x11Keysym(hotkey.KeyCode)
+		require.NoError(t, err, def)
+		assert.Equal(t, expected, keysym, def)
+	}
+
+	_, err := x11Keysym(0xFF)
+	assert.EqualError(t, err, "key 0xFF has no X11 keysym")
+}
+
+func TestX11Modifiers(t *testing.T) {
+	assert.Equal(t, uint(x11Mod1Mask), x11Modifiers(ModAlt), "Alt is not Ctrl")
+	assert.Equal(t, uint(x11ControlMask|x11ShiftMask|x11Mod4Mask), x11Modifiers(ModCtrl|ModShift|ModWin))
+}
+
+func TestX11GrabMasks(t *testing.T) {
+	const numLock = 1 << 4
+
+	assert.Equal(t, []uint{
+		x11Mod1Mask,
+		x11Mod1Mask | x11LockMask,
+		x11Mod1Mask | numLock,
+		x11Mod1Mask | x11LockMask | numLock,
+	}, x11GrabMasks(x11Mod1Mask, numLock))
+
+	assert.Equal(t, []uint{x11Mod1Mask, x11Mod1Mask | x11LockMask}, x11GrabMasks(x11Mod1Mask, 0),
+		"without a Num Lock modifier")
+}
@@ -0,0 +1,133 @@
+//go:build linux || freebsd || openbsd
+
+package api
+
+/*
+#cgo freebsd openbsd CFLAGS: -I/usr/X11R6/include -I/usr/local/include
+#cgo freebsd openbsd LDFLAGS: -L/usr/X11R6/lib -L/usr/local/lib
+#cgo freebsd openbsd LDFLAGS: -lX11 -lxkbcommon -lxkbcommon-x11 -lX11-xcb -lXcursor -lXfixes
+#cgo linux pkg-config: x11 xkbcommon xkbcommon-x11 x11-xcb xcursor xfixes
+
+#include <stdlib.h>
+#include <locale.h>
+#include <X11/Xlib.h>
+#include <X11/Xatom.h>
+#include <X11/Xutil.h>
+#include <X11/Xresource.h>
+#include <X11/XKBlib.h>
+#include <X11/Xlib-xcb.h>
+#include <X11/extensions/Xfixes.h>
+#include <X11/Xcursor/Xcursor.h>
+#include <xkbcommon/xkbcommon-x11.h>
+#include <X11/keysym.h>
+
+// grabError is the error of the last grab, X11 reports errors to a handler that is global to the process
+static int grabError;
+
+static int handleGrabError(Display *dpy, XErrorEvent *ev) {
+	grabError = ev->error_code;
+	return 0;
+}
+
+// grabKey grabs the key on the window and waits for the X server to accept or refuse the grab. Errors of other
+// requests of the process during the grab are lost.
+static int grabKey(Display *dpy, int keycode, unsigned int modifiers, Window window) {
+	grabError = Success;
+	XSync(dpy, False);
+	XErrorHandler previous = XSetErrorHandler(handleGrabError);
+	XGrabKey(dpy, keycode, modifiers, window, False, GrabModeAsync, GrabModeAsync);
+	XSync(dpy, False);
+	XSetErrorHandler(previous);
+	return grabError;
+}
+*/
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"runtime"
+	"time"
+	"unsafe"
+)
+
+// hotkeyPollInterval is how often the X11 event queue is checked for key presses and the hotkey for being stopped
+const hotkeyPollInterval = 50 * time.Millisecond
+
+// RegisterHotKey grabs the hotkey on the root window and calls pressed whenever it is pressed. It blocks until stop
+// is closed and ungrabs the hotkey before it returns. It returns an error right away if the hotkey cannot be grabbed,
+// e.g. because another application grabbed it.
+func RegisterHotKey(hotkey Hotkey, pressed func(), stop <-chan struct{}) error {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	keysym, err := x11Keysym(hotkey.KeyCode)
+	if err != nil {
+		return err
+	}
+
+	dpy := C.XOpenDisplay(nil)
+	if dpy == nil {
+		return errors.New("cannot open the X11 display")
+	}
+	defer C.XCloseDisplay(dpy)
+
+	root := C.XDefaultRootWindow(dpy)
+
+	keyCode := C.XKeysymToKeycode(dpy, C.KeySym(keysym))
+	if keyCode == 0 {
+		return fmt.Errorf("the keyboard has no key for %s", hotkey.String())
+	}
+
+	numLockMask := uint(C.XkbKeysymToModifiers(dpy, C.XK_Num_Lock))
+	masks := x11GrabMasks(x11Modifiers(hotkey.Modifiers), numLockMask)
+
+	// The variants that were grabbed are ungrabbed, also when a later variant cannot be grabbed
+	var grabbed []uint
+	defer func() {
+		for _, mask := range grabbed {
+			C.XUngrabKey(dpy, C.int(keyCode), C.uint(mask), root)
+		}
+		C.XSync(dpy, C.False)
+	}()
+
+	for _, mask := range masks {
+		switch code := C.grabKey(dpy, C.int(keyCode), C.uint(mask), root); code {
+		case C.Success:
+			grabbed = append(grabbed, mask)
+		case C.BadAccess:
+			return fmt.Errorf("%s is already grabbed by another application", hotkey.String())
+		default:
+			return fmt.Errorf("failed to grab %s: X11 error %d", hotkey.String(), int(code))
+		}
+	}
+
+	log.Printf("[DEBUG] Hotkey %s registered\n", hotkey.String())
+
+	// XNextEvent cannot be interrupted, so the queue is polled to notice that the hotkey is stopped
+	ticker := time.NewTicker(hotkeyPollInterval)
+	defer ticker.Stop()
+
+	for {
+		for C.XPending(dpy) > 0 {
+			var ev C.XEvent
+			C.XNextEvent(dpy, &ev)
+
+			if (*C.XAnyEvent)(unsafe.Pointer(&ev))._type != C.KeyPress {
+				continue
+			}
+			if (*C.XKeyEvent)(unsafe.Pointer(&ev)).keycode == C.uint(keyCode) {
+				log.Println("[DEBUG] Hotkey pressed")
+				pressed()
+			}
+		}
+
+		select {
+		case <-stop:
+			log.Printf("[DEBUG] Hotkey %s unregistered\n", hotkey.String())
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,18 @@
+//go:build (linux || freebsd || openbsd) && cgo
+
+package api
+
+import "testing"
+
+func Test_RegisterHotkey(t *testing.T) {
+	h := Hotkey{
+		Modifiers: ModAlt,
+		KeyCode:   'X',
+	}
+
+	// The hotkey is ungrabbed right away
+	stop := make(chan struct{})
+	close(stop)
+
+	RegisterHotKey(h, nil, stop)
+}
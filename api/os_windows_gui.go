@@ -22,9 +22,10 @@ const (
 )
 
 var (
-	modshell32 = windows.NewLazySystemDLL("shell32.dll")
-	moduser32  = windows.NewLazySystemDLL("user32.dll")
-	modgdi32   = windows.NewLazySystemDLL("Gdi32.dll")
+	modshell32  = windows.NewLazySystemDLL("shell32.dll")
+	moduser32   = windows.NewLazySystemDLL("user32.dll")
+	modgdi32    = windows.NewLazySystemDLL("Gdi32.dll")
+	modkernel32 = windows.NewLazySystemDLL("kernel32.dll")
 
 	procSHGetFileInfoW = modshell32.NewProc("SHGetFileInfoW")
 	procExtractIconW   = modshell32.NewProc("ExtractIconW")
@@ -460,19 +461,17 @@ func GetShellIconImage(path string) (im image.Image, err error) {
 	return
 }
 
-func ShellExecuteItem(v string) {
+func ShellExecuteItem(v string) error {
 	var program16 *uint16
 	var cmd16 *uint16
 	var args16 *uint16
 	var cwd16 *uint16
 
-	program16, _ = windows.UTF16PtrFromString(v)
+	program16, err := windows.UTF16PtrFromString(v)
+	if err != nil {
+		return err
+	}
 	cmd16, _ = windows.UTF16PtrFromString("open")
 
-	go func() {
-		err := windows.ShellExecute(0, cmd16, program16, args16, cwd16, windows.SW_SHOWNORMAL)
-		if err != nil {
-			log.Fatal(err)
-		}
-	}()
+	return windows.ShellExecute(0, cmd16, program16, args16, cwd16, windows.SW_SHOWNORMAL)
 }
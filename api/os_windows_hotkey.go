@@ -3,34 +3,58 @@
 package api
 
 import (
+	"errors"
 	windows "go-keyboard-launcher/api/internal"
 	"log"
 	"runtime"
 )
 
+const wmQuit = 0x0012
+
 var (
-	reghotkey = moduser32.NewProc("RegisterHotKey")
+	reghotkey         = moduser32.NewProc("RegisterHotKey")
+	unreghotkey       = moduser32.NewProc("UnregisterHotKey")
+	postThreadMessage = moduser32.NewProc("PostThreadMessageW")
+
+	getCurrentThreadId = modkernel32.NewProc("GetCurrentThreadId")
 )
 
-func RegisterHotKey(hotkey Hotkey, onHotKeyPressed func()) {
+// RegisterHotKey registers the hotkey system-wide and calls onHotKeyPressed whenever it is pressed. It blocks until
+// stop is closed and unregisters the hotkey before it returns. It returns an error right away if the hotkey cannot
+// be registered, e.g. because another application registered it.
+func RegisterHotKey(hotkey Hotkey, onHotKeyPressed func(), stop <-chan struct{}) error {
 	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
 
 	r1, _, err := reghotkey.Call(
 		0, 0, uintptr(hotkey.Modifiers), uintptr(hotkey.KeyCode))
 
 	if r1 != 1 {
-		log.Println("Failed to register", hotkey, ", error:", err)
-		return
+		return err
 	}
+	defer unreghotkey.Call(0, 0)
+
+	// The message loop only wakes up for messages, so a quit message is posted to it when the hotkey is stopped
+	threadId, _, _ := getCurrentThreadId.Call()
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		select {
+		case <-stop:
+			postThreadMessage.Call(threadId, wmQuit, 0, 0)
+		case <-done:
+		}
+	}()
 
 	msg := new(windows.Msg)
 	for {
 		switch ret := windows.GetMessage(msg, 0, 0, 0); ret {
 		case -1:
-			log.Printf("[ERROR] Got -1 from hotkey message loop, stopping the loop")
-			return
+			return errors.New("failed to get a message from the hotkey message loop")
 		case 0:
-			break
+			log.Printf("[DEBUG] Hotkey %s unregistered\n", hotkey.String())
+			return nil
 		default:
 			log.Printf("[DEBUG] Hotkey pressed\n")
 			onHotKeyPressed()
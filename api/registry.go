@@ -0,0 +1,83 @@
+package api
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Factory creates an instance of a plugin, the name is the name the instance is configured and identified by
+type Factory func(name string) Plugin
+
+type registration struct {
+	factory  Factory
+	typeOnly bool // The kind is only instantiated by sections that set it as their type
+}
+
+var (
+	factoriesMutex sync.Mutex
+	factories      = make(map[string]registration)
+)
+
+// Register makes a kind of plugin available to the launcher, plugins call it from an init function. It panics if the
+// kind is registered twice.
+func Register(kind string, factory Factory) {
+	register(kind, registration{factory: factory})
+}
+
+// RegisterType registers a kind of plugin that is not enabled by default, only the sections that set it as their type
+// are instances of it. It is meant for plugins that cannot do anything without configuration, e.g. running a command.
+func RegisterType(kind string, factory Factory) {
+	register(kind, registration{factory: factory, typeOnly: true})
+}
+
+func register(kind string, r registration) {
+	factoriesMutex.Lock()
+	defer factoriesMutex.Unlock()
+
+	if _, found := factories[kind]; found {
+		panic(fmt.Sprintf("plugin %s is registered twice", kind))
+	}
+
+	factories[kind] = r
+}
+
+// Registered returns the kinds of plugins that are registered, sorted by name
+func Registered() []string {
+	return kinds(func(registration) bool { return true })
+}
+
+// Defaults returns the kinds of plugins that are enabled when the configuration does not list the plugins, sorted by
+// name
+func Defaults() []string {
+	return kinds(func(r registration) bool { return !r.typeOnly })
+}
+
+func kinds(include func(registration) bool) []string {
+	factoriesMutex.Lock()
+	defer factoriesMutex.Unlock()
+
+	result := make([]string, 0, len(factories))
+	for kind, r := range factories {
+		if include(r) {
+			result = append(result, kind)
+		}
+	}
+	sort.Strings(result)
+
+	return result
+}
+
+// NewPlugin creates an instance of the kind of plugin with the given name, it returns false if the kind is not
+// registered
+func NewPlugin(kind string, name string) (Plugin, bool) {
+	factoriesMutex.Lock()
+	r, found := factories[kind]
+	factoriesMutex.Unlock()
+
+	if !found {
+		return nil, false
+	}
+
+	return r.factory(name), true
+}
@@ -0,0 +1,53 @@
+package api
+
+import (
+	"context"
+	"image"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+)
+
+type namedPlugin struct {
+	name string
+}
+
+func (p *namedPlugin) Initialize(hclog.Logger)                                     {}
+func (p *namedPlugin) Icon() *image.Image                                          { return nil }
+func (p *namedPlugin) Catalog(context.Context, CatalogCallback) error              { return nil }
+func (p *namedPlugin) Execute(context.Context, Item, string) error                 { return nil }
+func (p *namedPlugin) Name() string                                                { return p.name }
+func (p *namedPlugin) LoadConfig(func(interface{}) error)                          {}
+func (p *namedPlugin) Suggest(context.Context, string, []Item, SuggestionCallback) {}
+
+func TestRegistry(t *testing.T) {
+	Register("test-named", func(name string) Plugin {
+		return &namedPlugin{name: name}
+	})
+
+	assert.Contains(t, Registered(), "test-named")
+	assert.Panics(t, func() {
+		Register("test-named", nil)
+	})
+
+	p, ok := NewPlugin("test-named", "work")
+	assert.True(t, ok)
+	assert.Equal(t, "work", p.Name())
+
+	_, ok = NewPlugin("unknown", "unknown")
+	assert.False(t, ok)
+}
+
+func TestRegistry_Type(t *testing.T) {
+	RegisterType("test-type", func(name string) Plugin {
+		return &namedPlugin{name: name}
+	})
+
+	assert.Contains(t, Registered(), "test-type")
+	assert.NotContains(t, Defaults(), "test-type")
+
+	p, ok := NewPlugin("test-type", "scripts")
+	assert.True(t, ok)
+	assert.Equal(t, "scripts", p.Name())
+}
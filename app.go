@@ -3,16 +3,12 @@ package main
 import (
 	"context"
 	"fmt"
-	"image"
-	"log"
-	"os/exec"
-	"runtime"
-	"sort"
-	"strings"
 	"sync"
 	"time"
 
 	"go-keyboard-launcher/api"
+	"go-keyboard-launcher/api/freedesktop"
+	"go-keyboard-launcher/engine"
 
 	"gioui.org/app"
 	"gioui.org/io/event"
@@ -27,22 +23,35 @@ import (
 const WindowWidth = 600
 const SearchBoxHeight = 33
 
+// History entries older than this, or beyond the maximum number of entries, are pruned at startup
+const historyMaxAge = 180 * 24 * time.Hour
+const historyMaxEntries = 5000
+
+// App is the Gio frontend of the launcher, it renders the state of the search engine
 type App struct {
-	log       hclog.Logger
-	plugins   []api.Plugin
-	rootItems []InternalItem
-
-	// Item state
-	catalogMutex     sync.Mutex
-	suggestItems     []SuggestItem
-	suggestItemIndex int
-	itemStack        []StackEntry
-
-	lastVisibleItems     int           // Number of items that is shown
-	lastClickTime        time.Duration // Time of last click on item, to detect double clicks
-	lastSearchCancelFunc *context.CancelFunc
-	eventChannel         chan api.Event
-	hotKey               api.Hotkey
+	log    hclog.Logger
+	engine *engine.Engine
+
+	lastVisibleItems int           // Number of items that is shown
+	lastClickTime    time.Duration // Time of last click on item, to detect double clicks
+	eventChannel     chan api.Event
+
+	// The registered hotkeys, stopping them unregisters them. Guarded by hotkeyMutex.
+	hotkeyMutex   sync.Mutex
+	hotkeys       []hotkeyBinding
+	hotkeyStop    chan struct{}
+	hotkeyDone    *sync.WaitGroup
+	hotkeyChannel chan hotkeyBinding // The additional hotkeys that were pressed
+
+	// The configuration that is in effect and its problems, or the errors of the last attempt to reload it, and the
+	// hotkeys that could not be registered. Guarded by configMutex.
+	configMutex    sync.Mutex
+	config         loadedConfig
+	configProblems []configProblem
+	hotkeyProblems []configProblem
+
+	// The plugins the engine was started with, changing them requires a restart
+	instances []pluginInstance
 
 	// Gui state
 	isVisible  bool
@@ -51,55 +60,18 @@ type App struct {
 	eventKey   event.Tag
 }
 
-type InternalItem struct {
-	Item       api.Item
-	lookupName string
-	plugin     api.Plugin
-}
-
-type StackEntry struct {
-	item             InternalItem
-	searchText       string        // The text that was searched for
-	suggestItems     []SuggestItem // Then items that were suggested when pushed on the stack
-	suggestItemIndex int
-}
-
-type SuggestItem struct {
-	Item  InternalItem
-	Score float64
-}
-
-func (i InternalItem) DisplayName() string {
-	return i.Item.Label
-}
-
-func (i InternalItem) Icon() *image.Image {
-	if i.Item.Icon != nil {
-		return i.Item.Icon
-	} else if i.plugin.Icon() != nil {
-		return i.plugin.Icon()
-	} else {
-		return nil
-	}
-}
-
-func (i InternalItem) execute() {
-	i.plugin.Execute(i.Item)
-}
-
-func (i InternalItem) Description() string {
-	return i.Item.Description
-}
+// NewApp returns the frontend, its engine is created when the configuration is read since that decides which plugins
+// are enabled
+func NewApp() *App {
+	logger := hclog.New(&hclog.LoggerOptions{
+		Level: hclog.LevelFromString("DEBUG"),
+	})
 
-func NewApp(plugins []api.Plugin) *App {
 	a := App{
-		log: hclog.New(&hclog.LoggerOptions{
-			Level: hclog.LevelFromString("DEBUG"),
-		}),
-		plugins:          plugins,
-		isVisible:        false,
-		eventChannel:     make(chan api.Event),
-		suggestItemIndex: -1,
+		log:           logger,
+		isVisible:     false,
+		eventChannel:  make(chan api.Event),
+		hotkeyChannel: make(chan hotkeyBinding),
 	}
 
 	a.textInput.SetCaret(0, 0)
@@ -113,426 +85,226 @@ func NewApp(plugins []api.Plugin) *App {
 		Axis: layout.Vertical,
 	}}
 
-	// Initialize the plugins
-	for _, p := range plugins {
-		p.Initialize(a.log.Named(p.Name()))
-	}
-
 	return &a
 }
 
-func (a *App) Catalog() {
-	for _, p := range a.plugins {
-		if err := p.Catalog(context.Background()); err != nil {
-			a.log.Error(fmt.Sprintf("Failed to catalog plugin %s", p.Name()), err)
-		}
-	}
-}
-
-func (a *App) catalog() []InternalItem {
-	if a.rootItems == nil {
-		a.rebuildCatalog()
+// startEngine creates the engine with a plugin for each instance, in the order their results are listed
+func (a *App) startEngine(instances []pluginInstance) {
+	var plugins []api.Plugin
+	for _, instance := range instances {
+		p, _ := api.NewPlugin(instance.kind, instance.name)
+		plugins = append(plugins, p)
 	}
 
-	return a.rootItems
+	a.instances = instances
+	a.engine = engine.New(a.log, plugins)
+
+	a.engine.Subscribe(func(evt engine.Event) {
+		switch evt {
+		case engine.EventDismissed:
+			a.Hide()
+		case engine.EventFailed:
+			a.Show()
+		default:
+			go func() { a.eventChannel <- api.EventSuggestionsChanged }()
+		}
+	})
 }
 
-func (a *App) itemSuggest() {
-	if !a.HasCurrentItem() {
+// LoadHistory loads the usage history from the configuration directory, so frequently used items rank higher
+func (a *App) LoadHistory() {
+	h, err := engine.OpenHistory(HistoryFile())
+	if err != nil {
+		a.log.Error("Failed to load history, starting with an empty one", "error", err)
 		return
 	}
-	item := a.CurrentItem()
 
-	if item.Item.ArgsHint == api.Forbidden {
-		return
+	if err := h.Prune(historyMaxAge, historyMaxEntries); err != nil {
+		a.log.Error("Failed to prune history", "error", err)
 	}
 
-	// Push the item on the stack
-	a.itemStack = append(a.itemStack, StackEntry{
-		item:             item,
-		searchText:       a.textInput.Text(),
-		suggestItems:     a.suggestItems,
-		suggestItemIndex: a.suggestItemIndex,
-	})
-	a.resetInput()
+	a.engine.SetHistory(h)
+}
 
-	a.Search("")
+// Catalog shows the cached catalog right away and builds the catalogs of the plugins in the background
+func (a *App) Catalog() {
+	freedesktop.SetIconCacheDir(IconCacheDir())
+	a.engine.SetCatalogCache(engine.NewCatalogCache(CatalogCacheDir()))
+	a.engine.LoadCatalog()
+	a.engine.StartCatalogRefresh(context.Background())
+}
+
+func (a *App) itemSuggest() {
+	a.engine.Push()
 }
 
 func (a *App) itemDown() {
-	if a.suggestItemIndex == len(a.suggestItems)-1 {
+	if !a.engine.SelectNext() {
 		// We're at the end of the list
 		return
 	}
-	a.suggestItemIndex++
 
 	// Update the list scroll position if needed
+	index := a.engine.SelectedIndex()
 	p := a.listWidget.Position.First + a.lastVisibleItems
-	if a.suggestItemIndex >= p {
-		a.listWidget.Position.First = a.suggestItemIndex - a.lastVisibleItems + 1
+	if index >= p {
+		a.listWidget.Position.First = index - a.lastVisibleItems + 1
 	}
 }
 
 func (a *App) itemUp() {
-	if a.suggestItemIndex <= 0 {
+	if !a.engine.SelectPrevious() {
 		// We are already at the start of the list
 		return
 	}
-	a.suggestItemIndex--
 
-	if a.suggestItemIndex < a.listWidget.Position.First {
-		a.listWidget.Position.First = a.suggestItemIndex
+	index := a.engine.SelectedIndex()
+	if index < a.listWidget.Position.First {
+		a.listWidget.Position.First = index
 	}
 }
 
 func (a *App) cancel() {
-	if len(a.textInput.Text()) > 0 {
-		a.resetInput()
-	} else if len(a.itemStack) > 0 {
-		a.popItemStack()
-	} else {
-		a.Hide()
-	}
-}
-
-func (a *App) popItemStack() {
-	// Pop an item off the stack
-	top := a.itemStack[len(a.itemStack)-1]
-
-	a.itemStack = a.itemStack[:len(a.itemStack)-1]
-
-	// Restore the last search text
-	a.textInput.SetText(top.searchText)
-	a.textInput.SetCaret(len(top.searchText), len(top.searchText))
-
-	a.suggestItems = top.suggestItems
-	a.suggestItemIndex = top.suggestItemIndex
-
-	//a.Search(top.searchText)
-}
-
-func (a *App) resetInput() {
-	a.cancelLastSearch()
-	a.clearSuggestions()
-
-	a.textInput.SetText("")
+	a.engine.Cancel()
 }
 
 func (a *App) enter() {
-	if !a.HasCurrentItem() {
-		return
-	}
-	item := a.CurrentItem()
-
-	if item.Item.ArgsHint == api.Required {
-		// Push the item on the stack
-		a.itemStack = append(a.itemStack, StackEntry{
-			item:         item,
-			searchText:   a.textInput.Text(),
-			suggestItems: a.suggestItems,
-		})
-
-		a.resetInput()
-		return
-	}
-
-	switch item.Item.Category {
-	case api.File:
-		log.Printf("Launching file item %s", item.Item)
-		api.ShellExecuteItem(item.Item.Target)
-
-	case api.Url:
-		log.Printf("Launching URL item %s", item.Item)
-		executeUrl(item.Item.Target)
-
-	default:
-		if item.Item.Category >= api.User {
-			log.Printf("Executing user item %s", item.DisplayName())
-			go item.execute()
-		} else {
-			log.Printf("Error: Cannot handle this type of category")
-		}
-	}
-
-	a.Hide()
+	a.engine.Execute()
 }
 
-func executeUrl(url string) {
-	var err error
-
-	switch runtime.GOOS {
-	case "linux":
-		err = exec.Command("xdg-open", url).Start()
-	case "windows":
-		err = exec.Command("rundll32", "url.dll,FileProtocolHandler", url).Start()
-	case "darwin":
-		err = exec.Command("open", url).Start()
-	default:
-		err = fmt.Errorf("unsupported platform")
-	}
-
-	if err != nil {
-		log.Fatal(err)
+// syncInput updates the text field when the engine replaced the query, e.g. after popping the stack
+func (a *App) syncInput() {
+	if q := a.engine.Query(); q != a.textInput.Text() {
+		a.textInput.SetText(q)
+		a.textInput.SetCaret(len(q), len(q))
 	}
 }
 
-func (a *App) Search(input string) {
-	a.cancelLastSearch()
-
-	// Remove whitespace and lower for search matching
-	search := strings.ToLower(strings.Trim(input, " "))
-
-	// If the stack is empty
-	if len(a.itemStack) == 0 {
-		if len(search) == 0 {
-
-			// If there is no search query empty the list of suggestItems, we're done
-			a.clearSuggestions()
-			return
-
-		} else {
-			// Find items that match directly
-			var suggestions []SuggestItem
-
-			for _, item := range a.catalog() {
-				if s := MatchScore(search, item.lookupName); s > 0.0 {
-					suggestions = append(suggestions, SuggestItem{Item: item, Score: s})
-				}
-			}
-
-			a.setSuggestions(suggestions)
-		}
-	}
+func (a *App) doShow(w *app.Window) {
+	a.isVisible = true
 
-	// Dispatch search to plugins
-	ctx, cancel := context.WithCancel(context.Background())
-	a.lastSearchCancelFunc = &cancel
-
-	// Let the plugins do some suggestItems
-	if len(a.itemStack) > 0 {
-		p := a.itemStack[0].item.plugin
-		stack := collectItems(a.itemStack)
-
-		// The original user input is passed
-		go p.Suggest(ctx, input, stack, func(items []api.Item, match api.Match) {
-			internalItems := createSuggestions(items, match, p, search)
-			a.setSuggestions(internalItems)
-		})
-
-	} else {
-		// Broadcast the search query to all plugins
-		for _, p := range a.plugins {
-			go p.Suggest(ctx, input, nil, func(items []api.Item, match api.Match) {
-				internalItems := createSuggestions(items, match, p, search)
-				a.addSuggestions(internalItems)
-			})
-		}
-	}
+	w.Option(app.Size(unit.Dp(WindowWidth), unit.Dp(SearchBoxHeight)))
+	w.Perform(system.ActionCenter)
+	a.textInput.Focus()
 }
 
-func createSuggestions(items []api.Item, match api.Match, p api.Plugin, search string) []SuggestItem {
-	var internalItems []SuggestItem
-	for _, i := range items {
-		ii := asInternalItem(i, p)
-
-		var score float64
-		if match == api.MatchAny {
-			score = 1.0
+func (a *App) doHide(w *app.Window) {
+	a.isVisible = false
 
-		} else {
-			if len(search) == 0 {
-				score = 1.0
-			} else {
-				score = MatchScore(search, ii.lookupName)
+	w.Option(Hidden(true))
+	a.engine.Reset()
+	a.textInput.SetText("")
+}
 
-				if score == 0.0 {
-					continue
-				}
-			}
-		}
+func (a *App) run() error {
+	w := app.NewWindow(
+		app.Decorated(false),
+		app.Title("Go Anywhere"),
+		Hidden(!a.isVisible),
+	)
 
-		internalItems = append(internalItems, SuggestItem{
-			Item:  ii,
-			Score: score,
-		})
-	}
+	// Somehow, on Windows, setting the window size in NewWindow will create a window that is too large
+	w.Option(app.Size(unit.Dp(WindowWidth), unit.Dp(SearchBoxHeight)))
 
-	return internalItems
+	return a.eventLoop(w)
 }
 
-func collectItems(stack []StackEntry) []api.Item {
-	result := make([]api.Item, len(stack))
-	for idx, e := range stack {
-		result[idx] = e.item.Item
-	}
-	return result
-}
+func (a *App) registerHotkeys() {
+	a.hotkeyMutex.Lock()
+	defer a.hotkeyMutex.Unlock()
 
-func (a *App) cancelLastSearch() {
-	if a.lastSearchCancelFunc != nil {
-		// Cancel last search request
-		(*a.lastSearchCancelFunc)()
-		a.lastSearchCancelFunc = nil
-	}
-}
+	stop := make(chan struct{})
+	done := &sync.WaitGroup{}
+	a.hotkeyStop, a.hotkeyDone = stop, done
 
-func (a *App) HasCurrentItem() bool {
-	return a.suggestItemIndex != -1
-}
+	a.configMutex.Lock()
+	a.hotkeyProblems = nil
+	a.configMutex.Unlock()
 
-func (a *App) CurrentItem() InternalItem {
-	return a.suggestItems[a.suggestItemIndex].Item
-}
+	for _, binding := range a.hotkeys {
+		binding := binding
+		done.Add(1)
 
-func (a *App) rebuildCatalog() {
-	var catalog []InternalItem
+		// api.RegisterHotKey should run as a go routine since it locks to the OS thread to allow it to have a fixed
+		// thread listening to Windows API messages
+		go func() {
+			defer done.Done()
 
-	for _, plugin := range a.plugins {
-		items, err := plugin.GetItems()
+			err := api.RegisterHotKey(binding.hotkey, func() {
+				if binding.showsLauncher() {
+					a.eventChannel <- api.EventHotkey
+				} else {
+					a.hotkeyChannel <- binding
+				}
+			}, stop)
 
-		if err != nil {
-			fmt.Printf("Failed to load plugin x")
-		} else {
-			for _, each := range items {
-				catalog = append(catalog, asInternalItem(each, plugin))
+			if err != nil {
+				a.log.Error(fmt.Sprintf("Failed to register %s", binding.hotkey.String()), "error", err)
+				a.hotkeyFailed(binding, err)
 			}
-		}
+		}()
 	}
-
-	log.Println("Catalog rebuild")
-	a.rootItems = catalog
 }
 
-func (a *App) clearSuggestions() {
-	log.Println("clearSuggestions()")
-
-	a.catalogMutex.Lock()
-	a.suggestItems = nil
-	a.suggestItemIndex = -1
-	a.catalogMutex.Unlock()
+// hotkeyFailed reports a hotkey that could not be registered with the problems of the configuration and shows the
+// launcher, since the hotkey may be the one that opens it
+func (a *App) hotkeyFailed(binding hotkeyBinding, err error) {
+	a.configMutex.Lock()
+	a.hotkeyProblems = append(a.hotkeyProblems, configProblem{
+		message: fmt.Sprintf("cannot register the hotkey %s: %v", binding.hotkey.String(), err),
+	})
+	a.configMutex.Unlock()
 
-	go func() { a.eventChannel <- api.EventSuggestionsChanged }()
+	a.Show()
 }
 
-func (a *App) setSuggestions(suggestions []SuggestItem) {
-	log.Println("settingSuggestions(", len(suggestions), ")")
+// setHotkeys replaces the hotkeys, the previous ones are unregistered first if they were registered
+func (a *App) setHotkeys(bindings []hotkeyBinding) {
+	a.hotkeyMutex.Lock()
+	stop, done := a.hotkeyStop, a.hotkeyDone
+	a.hotkeys = bindings
+	a.hotkeyStop, a.hotkeyDone = nil, nil
+	a.hotkeyMutex.Unlock()
 
-	a.catalogMutex.Lock()
-	log.Println(" - acquired lock")
-
-	// Sort items
-	sort.SliceStable(suggestions, func(i, j int) bool {
-		return suggestions[i].Score > suggestions[j].Score
-	})
-
-	a.suggestItems = suggestions
-
-	// If we have any results, select the first item
-	if a.suggestItemIndex == -1 && len(suggestions) > 0 {
-		a.suggestItemIndex = 0
+	if stop == nil {
+		return
 	}
 
-	a.catalogMutex.Unlock()
-	log.Println(" - lock released")
+	close(stop)
+	done.Wait()
 
-	go func() { a.eventChannel <- api.EventSuggestionsChanged }()
+	a.registerHotkeys()
 }
 
-func (a *App) addSuggestions(suggestions []SuggestItem) {
-	log.Println("addSuggestions(", len(suggestions), ")")
-	if len(suggestions) == 0 {
-		return
-	}
-
-	// Deal with locking
-	a.catalogMutex.Lock()
-
-	var result []SuggestItem
+// doHotkey starts over with the plugin or the query of an additional hotkey and shows the launcher, or executes its
+// item. The launcher is only shown for an item that requires an argument or a sub-item, and to report a failure.
+func (a *App) doHotkey(w *app.Window, binding hotkeyBinding) {
+	var err error
+	show := true
 
-	for _, each := range suggestions {
-		result = append(result, each)
-	}
-	for _, each := range a.suggestItems {
-		result = append(result, each)
+	switch {
+	case len(binding.subItem) > 0:
+		err = a.engine.OpenItem(binding.item, binding.subItem)
+	case len(binding.item) > 0:
+		show, err = a.engine.ExecuteIdentity(binding.item)
+	default:
+		err = a.engine.Open(binding.plugin, binding.query)
 	}
 
-	// Sort items
-	sort.SliceStable(result, func(i, j int) bool {
-		return result[i].Score > result[j].Score
-	})
-
-	log.Println("Found some extra suggestItems")
-	a.suggestItems = result
-
-	// If we have any results, select the first item
-	if a.suggestItemIndex == -1 && len(result) > 0 {
-		a.suggestItemIndex = 0
+	if err != nil {
+		a.log.Error(fmt.Sprintf("Failed to run the hotkey %s", binding.hotkey.String()), "error", err)
+		a.engine.ShowFailure(binding.hotkey.String(), err)
+		show = true
 	}
 
-	a.catalogMutex.Unlock()
-
-	go func() { a.eventChannel <- api.EventSuggestionsChanged }()
-}
-
-func (a *App) doShow(w *app.Window) {
-	a.isVisible = true
-
-	w.Option(app.Size(unit.Dp(WindowWidth), unit.Dp(SearchBoxHeight)))
-	w.Perform(system.ActionCenter)
-	a.textInput.Focus()
-}
-
-func (a *App) doHide(w *app.Window) {
-	a.isVisible = false
-
-	w.Option(Hidden(true))
-	a.resetStack()
-	a.resetInput()
-}
-
-func (a *App) resetStack() {
-	a.itemStack = nil
-}
-
-func (a *App) run() error {
-	w := app.NewWindow(
-		app.Decorated(false),
-		app.Title("Go Anywhere"),
-		Hidden(!a.isVisible),
-	)
-
-	// Somehow, on Windows, setting the window size in NewWindow will create a window that is too large
-	w.Option(app.Size(unit.Dp(WindowWidth), unit.Dp(SearchBoxHeight)))
-
-	return a.eventLoop(w)
-}
-
-func (a *App) registerHotkey() {
-	// api.RegisterHotKey should run as a go routine since it locks to the OS thread to allow it to have a fixed
-	// thread listening to Windows API messages
-	go api.RegisterHotKey(a.hotKey, func() {
-		a.eventChannel <- api.EventHotkey
-	})
-}
-
-func (a *App) pluginByName(k string) (api.Plugin, bool) {
-	for _, each := range a.plugins {
-		if each.Name() == k {
-			return each, true
-		}
+	if !show {
+		return
 	}
 
-	return nil, false
+	a.syncInput()
+	a.doShow(w)
 }
 
 func (a *App) Quit() {
 	a.eventChannel <- api.EventQuit
 }
-
-func asInternalItem(each api.Item, plugin api.Plugin) InternalItem {
-	return InternalItem{
-		Item:       each,
-		lookupName: strings.ToLower(each.Label),
-		plugin:     plugin,
-	}
-}
@@ -1,13 +1,19 @@
 package main
 
 import (
+	"context"
 	_ "embed"
 	"errors"
 	"log"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
 
 	"go-keyboard-launcher/api"
+	"go-keyboard-launcher/engine"
 
 	"github.com/BurntSushi/toml"
 )
@@ -16,8 +22,36 @@ import (
 var configExampleData []byte
 
 type config struct {
-	Hotkey  string
-	Plugins map[string]toml.Primitive `toml:"plugin"`
+	Hotkey          string
+	Hotkeys         []hotkeyConfig            `toml:"hotkeys"`
+	Enabled         []string                  `toml:"plugins"` // Nil enables all plugins
+	SuggestTimeout  time.Duration             `toml:"suggest_timeout"`
+	CatalogTimeout  time.Duration             `toml:"catalog_timeout"`
+	ExecuteTimeout  time.Duration             `toml:"execute_timeout"`
+	RefreshInterval time.Duration             `toml:"refresh_interval"`
+	Prefixes        map[string]string         `toml:"prefixes"`
+	Aliases         map[string]string         `toml:"aliases"`
+	Plugins         map[string]toml.Primitive `toml:"plugin"`
+}
+
+// hotkeyConfig is a [[hotkeys]] table, an additional hotkey that opens the launcher limited to a plugin or with a
+// query, that executes a catalog item without showing the launcher, or that opens a suggestion of a catalog item
+type hotkeyConfig struct {
+	Key     string `toml:"key"`
+	Plugin  string `toml:"plugin"`
+	Query   string `toml:"query"`
+	Item    string `toml:"item"`    // The identity of the item, "<plugin>:<item id>"
+	SubItem string `toml:"subitem"` // The ID of the suggestion of the item to open, e.g. "pulls" of a repository
+}
+
+// pluginConfig holds the settings of a [plugin.<name>] section that are handled by the launcher instead of the plugin
+type pluginConfig struct {
+	Type            string        `toml:"type"` // The kind of plugin, if the section is another instance of it
+	Priority        int           `toml:"priority"`
+	SuggestTimeout  time.Duration `toml:"suggest_timeout"`
+	CatalogTimeout  time.Duration `toml:"catalog_timeout"`
+	ExecuteTimeout  time.Duration `toml:"execute_timeout"`
+	RefreshInterval time.Duration `toml:"refresh_interval"`
 }
 
 func ConfigDir() string {
@@ -33,6 +67,18 @@ func ConfigFile() string {
 	return filepath.Join(ConfigDir(), "config.toml")
 }
 
+func HistoryFile() string {
+	return filepath.Join(ConfigDir(), "history.json")
+}
+
+func CatalogCacheDir() string {
+	return filepath.Join(ConfigDir(), "cache", "catalog")
+}
+
+func IconCacheDir() string {
+	return filepath.Join(ConfigDir(), "cache", "icons")
+}
+
 func (a *App) ReadConfiguration() error {
 	err := ensureDirectoryExists(ConfigDir())
 	if err != nil {
@@ -49,38 +95,369 @@ func (a *App) ReadConfiguration() error {
 		}
 	}
 
-	err = a.readConfigFile(f)
+	a.readConfigFile(f)
+	return nil
+}
+
+// defaultHotkey is used when the configuration does not set a valid hotkey
+const defaultHotkey = "Alt+Space"
+
+// loadedConfig is a decoded configuration file, everything in it is decoded before it is applied so a broken file
+// does not leave the launcher half configured
+type loadedConfig struct {
+	base     config
+	md       toml.MetaData
+	lines    map[string]int  // The line of every key, for reporting problems
+	hotkey   *api.Hotkey     // Nil if the hotkey is invalid
+	hotkeys  []hotkeyBinding // The valid [[hotkeys]] tables
+	options  map[string]engine.PluginOptions
+	sections map[string]map[string]interface{} // The [plugin.<name>] sections, to tell which plugins changed
+
+	instances []pluginInstance // The enabled plugins in the order their results are listed
+
+	// The problems found while decoding and the keys that no one decoded while applying the configuration
+	problems []configProblem
+	unknown  []toml.Key
+}
+
+// pluginInstance is an enabled plugin, the name of its section and the kind it was registered as. The name and the
+// kind are the same unless the section sets the type, e.g. a second GitHub account.
+type pluginInstance struct {
+	name string
+	kind string
+}
+
+// readConfigFile applies the configuration file. A file that cannot be decoded is reported and the defaults are
+// applied instead, so the launcher can still be used to fix it.
+func (a *App) readConfigFile(filename string) {
+	c, err := a.decodeConfigFile(filename)
 	if err != nil {
-		return err
+		a.log.Error("Failed to read the configuration, starting with the defaults", "error", err)
+
+		c, _ = a.decodeConfig("")
+		c.problems = decodeProblems(err)
 	}
 
-	return nil
+	a.applyConfig(c, nil)
 }
 
-func (a *App) processConfig(c config) {
-	a.hotKey = api.ParseHotkey(c.Hotkey)
-	log.Printf("[DEBUG] Configured hot key %s\n", a.hotKey.String())
+func (a *App) decodeConfigFile(filename string) (loadedConfig, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return loadedConfig{}, err
+	}
 
+	return a.decodeConfig(string(data))
 }
 
-func (a *App) readConfigFile(filename string) error {
-	base := config{}
+func (a *App) decodeConfig(data string) (loadedConfig, error) {
+	c := loadedConfig{
+		lines:    keyLines(data),
+		options:  make(map[string]engine.PluginOptions),
+		sections: make(map[string]map[string]interface{}),
+	}
 
-	md, err := toml.DecodeFile(filename, &base)
+	md, err := toml.Decode(data, &c.base)
 	if err != nil {
-		return err
+		return loadedConfig{}, err
+	}
+	c.md = md
+
+	// The sections are decoded separately, so their keys still count as undecoded until the plugins decode them
+	var sections struct {
+		Plugins map[string]map[string]interface{} `toml:"plugin"`
+	}
+	if _, err := toml.Decode(data, &sections); err != nil {
+		return loadedConfig{}, err
+	}
+
+	if len(c.base.Hotkey) == 0 {
+		c.base.Hotkey = defaultHotkey
 	}
 
-	a.processConfig(base)
+	if hotkey, err := api.ParseHotkey(c.base.Hotkey); err != nil {
+		c.problem(toml.Key{"hotkey"}, false, "invalid hotkey %q: %s", c.base.Hotkey, err)
+	} else {
+		c.hotkey = &hotkey
+	}
+
+	defaults := engine.PluginOptions{
+		SuggestTimeout:  c.base.SuggestTimeout,
+		CatalogTimeout:  c.base.CatalogTimeout,
+		ExecuteTimeout:  c.base.ExecuteTimeout,
+		RefreshInterval: c.base.RefreshInterval,
+	}
+	c.checkOptions(nil, &defaults)
 
-	for k, prim := range base.Plugins {
-		p, found := a.pluginByName(k)
-		if found {
-			p.LoadConfig(func(i interface{}) error {
-				return md.PrimitiveDecode(prim, i)
-			})
+	settings := make(map[string]pluginConfig)
+	for _, name := range sortedKeys(c.base.Plugins) {
+		pc := pluginConfig{}
+		if err := md.PrimitiveDecode(c.base.Plugins[name], &pc); err != nil {
+			c.problems = append(c.problems, decodeProblems(err)...)
 		}
+		settings[name] = pc
 	}
 
-	return nil
+	c.resolveInstances(settings)
+
+	for _, instance := range c.instances {
+		options := defaults
+
+		if pc, found := settings[instance.name]; found {
+			c.sections[instance.name] = sections.Plugins[instance.name]
+
+			overrides := engine.PluginOptions{
+				SuggestTimeout:  pc.SuggestTimeout,
+				CatalogTimeout:  pc.CatalogTimeout,
+				ExecuteTimeout:  pc.ExecuteTimeout,
+				RefreshInterval: pc.RefreshInterval,
+			}
+			c.checkOptions(toml.Key{"plugin", instance.name}, &overrides)
+
+			if overrides.SuggestTimeout > 0 {
+				options.SuggestTimeout = overrides.SuggestTimeout
+			}
+			if overrides.CatalogTimeout > 0 {
+				options.CatalogTimeout = overrides.CatalogTimeout
+			}
+			if overrides.ExecuteTimeout > 0 {
+				options.ExecuteTimeout = overrides.ExecuteTimeout
+			}
+			if overrides.RefreshInterval > 0 {
+				options.RefreshInterval = overrides.RefreshInterval
+			}
+			options.Priority = pc.Priority
+		}
+
+		c.options[instance.name] = options
+	}
+
+	c.checkPlugins(settings)
+	c.decodeHotkeys()
+
+	return c, nil
+}
+
+// hotkeyBinding is a registered hotkey and what it does, the main hotkey only shows the launcher
+type hotkeyBinding struct {
+	hotkey  api.Hotkey
+	plugin  string
+	query   string
+	item    string
+	subItem string
+}
+
+// showsLauncher returns true if the hotkey only shows the launcher as it was left
+func (b hotkeyBinding) showsLauncher() bool {
+	return len(b.plugin)+len(b.query)+len(b.item)+len(b.subItem) == 0
+}
+
+// decodeHotkeys checks the [[hotkeys]] tables, the tables with an invalid or duplicate key or without a valid action
+// are left out. The tables of plugins that are not enabled are kept with a warning.
+func (c *loadedConfig) decodeHotkeys() {
+	known := make(map[string]bool)
+	for _, instance := range c.instances {
+		known[instance.name] = true
+	}
+
+	bound := make(map[api.Hotkey]bool)
+	if c.hotkey != nil {
+		bound[*c.hotkey] = true
+	}
+
+	for idx, each := range c.base.Hotkeys {
+		table := toml.Key{"hotkeys", strconv.Itoa(idx)}
+		key := append(append(toml.Key{}, table...), "key")
+
+		if len(each.Key) == 0 {
+			c.problem(table, false, "hotkey %d has no key", idx+1)
+			continue
+		}
+
+		hotkey, err := api.ParseHotkey(each.Key)
+		switch {
+		case err != nil:
+			c.problem(key, false, "invalid hotkey %q: %s", each.Key, err)
+			continue
+		case bound[hotkey]:
+			c.problem(key, false, "hotkey %q is already bound", each.Key)
+			continue
+		case len(each.Item) > 0 && len(each.Plugin)+len(each.Query) > 0:
+			c.problem(table, false, "hotkey %q must either execute an item or open a plugin or query", each.Key)
+			continue
+		case len(each.SubItem) > 0 && len(each.Item) == 0:
+			c.problem(append(table, "subitem"), false, "hotkey %q opens a sub-item without an item", each.Key)
+			continue
+		case len(each.Item)+len(each.Plugin)+len(each.Query) == 0:
+			c.problem(table, false, "hotkey %q has no plugin, query or item", each.Key)
+			continue
+		}
+
+		// A plugin or item that is not available is only a warning, like the prefixes and aliases
+		parts := strings.SplitN(each.Item, ":", 2)
+		switch {
+		case len(each.Plugin) > 0 && !known[each.Plugin]:
+			c.problem(append(table, "plugin"), true, "hotkey %q opens unknown or disabled plugin %q", each.Key,
+				each.Plugin)
+		case len(each.Item) > 0 && len(parts) < 2:
+			c.problem(append(table, "item"), true, "hotkey %q must execute an item as \"<plugin>:<item id>\"",
+				each.Key)
+		case len(each.Item) > 0 && !known[parts[0]]:
+			c.problem(append(table, "item"), true, "hotkey %q executes an item of unknown or disabled plugin %q",
+				each.Key, parts[0])
+		}
+
+		bound[hotkey] = true
+		c.hotkeys = append(c.hotkeys, hotkeyBinding{
+			hotkey:  hotkey,
+			plugin:  each.Plugin,
+			query:   each.Query,
+			item:    each.Item,
+			subItem: each.SubItem,
+		})
+	}
+}
+
+// bindings returns the main hotkey followed by the additional ones
+func (c *loadedConfig) bindings() []hotkeyBinding {
+	return append([]hotkeyBinding{{hotkey: *c.hotkey}}, c.hotkeys...)
+}
+
+// applyConfig puts the configuration into effect and returns the plugins whose section changed compared to the
+// previous configuration, or all plugins with a section if there is no previous configuration
+func (a *App) applyConfig(c loadedConfig, previous *loadedConfig) []api.Plugin {
+	if a.engine == nil {
+		a.startEngine(c.instances)
+	} else if !reflect.DeepEqual(c.instances, a.instances) {
+		c.problem(toml.Key{"plugins"}, true, "the enabled plugins changed, restart the launcher to apply this")
+	}
+
+	// An invalid hotkey keeps the one that is registered
+	if c.hotkey == nil && previous != nil {
+		c.hotkey = previous.hotkey
+	} else if c.hotkey == nil {
+		hotkey, _ := api.ParseHotkey(defaultHotkey)
+		c.hotkey = &hotkey
+	}
+
+	if previous == nil || !reflect.DeepEqual(c.bindings(), previous.bindings()) {
+		a.setHotkeys(c.bindings())
+		log.Printf("[DEBUG] Configured hot key %s and %d additional hot keys\n", c.hotkey.String(), len(c.hotkeys))
+	}
+
+	a.engine.SetPrefixes(c.base.Prefixes)
+	a.engine.SetAliases(c.base.Aliases)
+
+	var changed []api.Plugin
+	loaded := make(map[string]bool)
+
+	for _, p := range a.engine.Plugins() {
+		a.engine.SetPluginOptions(p.Name(), c.options[p.Name()])
+
+		prim, found := c.base.Plugins[p.Name()]
+		if previous != nil && reflect.DeepEqual(c.sections[p.Name()], previous.sections[p.Name()]) {
+			// The plugin does not decode its section again, so its unknown keys are the same as before
+			c.unknown = append(c.unknown, keysIn(previous.unknown, toml.Key{"plugin", p.Name()})...)
+			continue
+		} else if previous == nil && !found {
+			continue
+		}
+
+		a.engine.ConfigurePlugin(p, func(i interface{}) error {
+			if !found {
+				// The section was removed, the plugin gets an empty one
+				_, err := toml.Decode("", i)
+				return err
+			}
+
+			err := c.md.PrimitiveDecode(prim, i)
+			if err != nil {
+				c.problems = append(c.problems, decodeProblems(err)...)
+			}
+
+			return err
+		})
+
+		loaded[p.Name()] = true
+		changed = append(changed, p)
+	}
+
+	c.unknown = append(c.unknown, a.undecodedKeys(c, loaded)...)
+
+	a.configMutex.Lock()
+	a.config = c
+	a.configProblems = c.allProblems()
+	a.configMutex.Unlock()
+
+	for _, problem := range a.configProblems {
+		a.log.Warn("Problem in the configuration", "problem", problem.String())
+	}
+
+	return changed
+}
+
+// configPollInterval is how often the configuration file is checked for changes
+const configPollInterval = time.Second
+
+// WatchConfiguration reloads the configuration file whenever it changes, until the context is done
+func (a *App) WatchConfiguration(ctx context.Context) {
+	f := ConfigFile()
+	last, _ := os.Stat(f)
+
+	ticker := time.NewTicker(configPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		// Editors may replace the file while saving, a missing file is not a change
+		info, err := os.Stat(f)
+		if err != nil || (last != nil && info.ModTime().Equal(last.ModTime()) && info.Size() == last.Size()) {
+			continue
+		}
+
+		last = info
+		a.reloadConfiguration(f)
+	}
+}
+
+// reloadConfiguration applies the configuration file again and rebuilds the catalogs of the plugins whose section
+// changed. A file that cannot be decoded is reported and the previous configuration stays in effect.
+func (a *App) reloadConfiguration(filename string) {
+	c, err := a.decodeConfigFile(filename)
+	if err != nil {
+		a.log.Error("Failed to reload the configuration, keeping the previous one", "error", err)
+
+		a.configMutex.Lock()
+		a.configProblems = decodeProblems(err)
+		a.configMutex.Unlock()
+
+		go func() { a.eventChannel <- api.EventSuggestionsChanged }()
+		return
+	}
+
+	a.configMutex.Lock()
+	previous := a.config
+	a.configMutex.Unlock()
+
+	a.log.Info("Configuration reloaded")
+
+	for _, p := range a.applyConfig(c, &previous) {
+		go a.engine.RefreshCatalog(p)
+	}
+
+	go func() { a.eventChannel <- api.EventSuggestionsChanged }()
+}
+
+// ConfigProblems returns the problems of the configuration that is in effect, or the errors of the last attempt to
+// reload it if that failed, followed by the hotkeys that could not be registered
+func (a *App) ConfigProblems() []configProblem {
+	a.configMutex.Lock()
+	defer a.configMutex.Unlock()
+
+	return append(append([]configProblem(nil), a.configProblems...), a.hotkeyProblems...)
 }
@@ -0,0 +1,415 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-keyboard-launcher/api"
+	"go-keyboard-launcher/engine"
+
+	"github.com/BurntSushi/toml"
+)
+
+// configProblem is a problem in the configuration file. Errors are values that cannot be used, warnings are keys that
+// are ignored.
+type configProblem struct {
+	line    int // The line in the configuration file, 0 if it is not known
+	message string
+	warning bool
+}
+
+func (p configProblem) String() string {
+	return p.format(filepath.Base(ConfigFile()))
+}
+
+// format describes the problem for the file, e.g. `config.toml:3: warning: unknown key "hotky"`
+func (p configProblem) format(file string) string {
+	kind := "error"
+	if p.warning {
+		kind = "warning"
+	}
+
+	if p.line > 0 {
+		return fmt.Sprintf("%s:%d: %s: %s", file, p.line, kind, p.message)
+	}
+
+	return fmt.Sprintf("%s: %s: %s", file, kind, p.message)
+}
+
+// problem records a problem with the value of the key
+func (c *loadedConfig) problem(key toml.Key, warning bool, format string, args ...interface{}) {
+	c.problems = append(c.problems, configProblem{
+		line:    c.lines[key.String()],
+		message: fmt.Sprintf(format, args...),
+		warning: warning,
+	})
+}
+
+// allProblems returns the problems and the unknown keys ordered by line
+func (c *loadedConfig) allProblems() []configProblem {
+	result := append([]configProblem(nil), c.problems...)
+
+	for _, key := range c.unknown {
+		result = append(result, configProblem{
+			line:    c.lines[key.String()],
+			message: fmt.Sprintf("unknown key %q", key.String()),
+			warning: true,
+		})
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].line < result[j].line
+	})
+
+	return result
+}
+
+// checkOptions reports negative durations of the options of a [plugin.<name>] section, or the top level if the
+// section is nil, and resets them to the default
+func (c *loadedConfig) checkOptions(section toml.Key, o *engine.PluginOptions) {
+	for _, each := range []struct {
+		key   string
+		field *time.Duration
+	}{
+		{"suggest_timeout", &o.SuggestTimeout},
+		{"catalog_timeout", &o.CatalogTimeout},
+		{"execute_timeout", &o.ExecuteTimeout},
+		{"refresh_interval", &o.RefreshInterval},
+	} {
+		if *each.field < 0 {
+			key := append(append(toml.Key{}, section...), each.key)
+			c.problem(key, false, "%s must not be negative", key.String())
+			*each.field = 0
+		}
+	}
+}
+
+// resolveInstances decides which plugins are enabled and in which order. Without a plugins list the plugins that are
+// enabled by default are, followed by the sections that set the type of another plugin.
+func (c *loadedConfig) resolveInstances(settings map[string]pluginConfig) {
+	registered := make(map[string]bool)
+	for _, kind := range api.Registered() {
+		registered[kind] = true
+	}
+
+	names := c.base.Enabled
+	if names == nil {
+		names = api.Defaults()
+		for _, name := range sortedKeys(settings) {
+			if len(settings[name].Type) > 0 && !registered[name] {
+				names = append(names, name)
+			}
+		}
+	}
+
+	enabled := make(map[string]bool)
+	for _, name := range names {
+		kind := name
+		if len(settings[name].Type) > 0 {
+			kind = settings[name].Type
+		}
+
+		switch {
+		case enabled[name]:
+			c.problem(toml.Key{"plugins"}, false, "plugin %q is enabled more than once", name)
+		case !registered[kind] && kind != name:
+			c.problem(toml.Key{"plugin", name, "type"}, false, "unknown plugin type %q, the types are %s", kind,
+				strings.Join(api.Registered(), ", "))
+		case !registered[kind]:
+			c.problem(toml.Key{"plugins"}, false, "unknown plugin %q, the plugins are %s", name,
+				strings.Join(api.Registered(), ", "))
+		default:
+			c.instances = append(c.instances, pluginInstance{name: name, kind: kind})
+		}
+
+		enabled[name] = true
+	}
+}
+
+// checkPlugins reports sections of plugins that are not enabled, and prefixes and aliases of plugins that are not
+// enabled
+func (c *loadedConfig) checkPlugins(settings map[string]pluginConfig) {
+	registered := make(map[string]bool)
+	for _, kind := range api.Registered() {
+		registered[kind] = true
+	}
+
+	var names []string
+	known := make(map[string]bool)
+	for _, instance := range c.instances {
+		names = append(names, instance.name)
+		known[instance.name] = true
+	}
+
+	for _, name := range sortedKeys(c.base.Plugins) {
+		if known[name] {
+			continue
+		}
+
+		if len(settings[name].Type) > 0 || registered[name] {
+			c.problem(toml.Key{"plugin", name}, true, "plugin %q is configured but not enabled", name)
+		} else {
+			c.problem(toml.Key{"plugin", name}, true, "unknown plugin %q, the plugins are %s", name,
+				strings.Join(names, ", "))
+		}
+	}
+
+	for _, keyword := range sortedKeys(c.base.Prefixes) {
+		target := c.base.Prefixes[keyword]
+		if plugin := strings.SplitN(target, ":", 2)[0]; !known[plugin] {
+			c.problem(toml.Key{"prefixes", keyword}, true, "prefix %q targets unknown or disabled plugin %q", keyword,
+				plugin)
+		}
+	}
+
+	for _, alias := range sortedKeys(c.base.Aliases) {
+		target := c.base.Aliases[alias]
+		parts := strings.SplitN(target, ":", 2)
+
+		if len(parts) < 2 {
+			c.problem(toml.Key{"aliases", alias}, true,
+				"alias %q must target an item as \"<plugin>:<item id>\", e.g. \"win32-apps:Outlook\"", alias)
+		} else if !known[parts[0]] {
+			c.problem(toml.Key{"aliases", alias}, true, "alias %q targets unknown or disabled plugin %q", alias,
+				parts[0])
+		}
+	}
+}
+
+// undecodedKeys returns the keys that neither the launcher nor the plugins whose section was loaded decoded. Keys of
+// unknown plugins are already reported as such.
+func (a *App) undecodedKeys(c loadedConfig, loaded map[string]bool) []toml.Key {
+	var result []toml.Key
+
+	for _, key := range c.md.Undecoded() {
+		if len(key) >= 2 && key[0] == "plugin" && !loaded[key[1]] {
+			continue
+		}
+
+		result = append(result, key)
+	}
+
+	return result
+}
+
+// keysIn returns the keys that are in the table
+func keysIn(keys []toml.Key, table toml.Key) []toml.Key {
+	var result []toml.Key
+
+	for _, key := range keys {
+		if len(key) > len(table) && toml.Key(key[:len(table)]).String() == table.String() {
+			result = append(result, key)
+		}
+	}
+
+	return result
+}
+
+// decodeProblems returns the error of decoding the configuration file as a problem. Syntax errors and values the
+// decoder could not convert, e.g. an invalid duration, have a position. Other errors, e.g. a value of the wrong type,
+// only describe where they are in their message.
+func decodeProblems(err error) []configProblem {
+	var pe toml.ParseError
+	if !errors.As(err, &pe) {
+		return []configProblem{{message: strings.TrimPrefix(err.Error(), "toml: ")}}
+	}
+
+	message := pe.Message
+	if len(message) == 0 {
+		// The error of a conversion is only part of the text of the error
+		pe.LastKey = ""
+		message = strings.TrimPrefix(pe.Error(), fmt.Sprintf("toml: line %d: ", pe.Position.Line))
+	}
+
+	return []configProblem{{line: pe.Position.Line, message: message}}
+}
+
+// keyLines returns the line of every table and key in the TOML data. It understands enough of TOML to find the keys
+// of a configuration file:
+//
+//   - The keys of an array of tables are numbered, e.g. "hotkeys.0.key", and are also recorded without the number at
+//     their first table, since the decoder reports unknown keys of an array that way.
+//   - The lines inside multi-line strings are skipped.
+//   - Only the key of an inline table or of an array of inline tables is found, not the keys inside it.
+//
+// Keys that are not found are reported without a line.
+func keyLines(data string) map[string]int {
+	result := make(map[string]int)
+	counts := make(map[string]int) // The number of tables of every array of tables
+	var table, array toml.Key
+	var multiline string // The delimiter of the multi-line string the line is in, if any
+
+	for idx, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+
+		switch {
+		case len(multiline) > 0:
+			if strings.Contains(line, multiline) {
+				multiline = ""
+			}
+			continue
+		case len(line) == 0 || strings.HasPrefix(line, "#"):
+			continue
+		case strings.HasPrefix(line, "["):
+			end := strings.LastIndex(line, "]")
+			if end < 0 {
+				continue
+			}
+
+			table = splitKey(strings.Trim(line[:end], "[] \t"))
+			if _, found := result[table.String()]; !found {
+				result[table.String()] = idx + 1
+			}
+
+			// The tables of an array are numbered, the decoder reports their unknown keys without the number
+			if strings.HasPrefix(line, "[[") {
+				array = table
+				table = append(append(toml.Key{}, table...), strconv.Itoa(counts[table.String()]))
+				counts[array.String()]++
+				result[table.String()] = idx + 1
+			} else {
+				array = nil
+			}
+		default:
+			eq := indexUnquoted(line, '=')
+			if eq < 0 {
+				continue
+			}
+
+			key := append(append(toml.Key{}, table...), splitKey(line[:eq])...)
+			result[key.String()] = idx + 1
+
+			if array != nil {
+				key = append(append(toml.Key{}, array...), splitKey(line[:eq])...)
+				if _, found := result[key.String()]; !found {
+					result[key.String()] = idx + 1
+				}
+			}
+
+			// A multi-line string that is not closed on the line of its key continues on the next lines
+			for _, delimiter := range []string{`"""`, "'''"} {
+				if strings.Count(line[eq:], delimiter)%2 == 1 {
+					multiline = delimiter
+				}
+			}
+		}
+	}
+
+	return result
+}
+
+// splitKey splits a dotted TOML key into its parts and removes their quotes
+func splitKey(s string) toml.Key {
+	var key toml.Key
+
+	for {
+		dot := indexUnquoted(s, '.')
+		part := s
+		if dot >= 0 {
+			part = s[:dot]
+		}
+
+		part = strings.TrimSpace(part)
+		if unquoted, err := strconv.Unquote(part); err == nil {
+			part = unquoted
+		} else {
+			part = strings.Trim(part, "'")
+		}
+		key = append(key, part)
+
+		if dot < 0 {
+			return key
+		}
+		s = s[dot+1:]
+	}
+}
+
+// indexUnquoted returns the index of the first c in s that is not inside quotes, or -1
+func indexUnquoted(s string, c byte) int {
+	var quote byte
+
+	for idx := 0; idx < len(s); idx++ {
+		switch {
+		case quote != 0 && s[idx] == '\\' && quote == '"':
+			idx++
+		case quote != 0 && s[idx] == quote:
+			quote = 0
+		case quote == 0 && (s[idx] == '"' || s[idx] == '\''):
+			quote = s[idx]
+		case quote == 0 && s[idx] == c:
+			return idx
+		}
+	}
+
+	return -1
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	result := make([]string, 0, len(m))
+	for k := range m {
+		result = append(result, k)
+	}
+	sort.Strings(result)
+
+	return result
+}
+
+// checkSections decodes the sections of the enabled plugins into the types their plugins load them into and records
+// the keys that no one decodes, like applying the configuration does, but without initializing the plugins
+func (a *App) checkSections(c *loadedConfig) {
+	loaded := make(map[string]bool)
+
+	for _, instance := range c.instances {
+		prim, found := c.base.Plugins[instance.name]
+		if !found {
+			continue
+		}
+
+		// Plugins without settings of their own decode nothing, so all the keys of their section are unknown
+		p, _ := api.NewPlugin(instance.kind, instance.name)
+		if checker, ok := p.(api.ConfigChecker); ok {
+			if err := c.md.PrimitiveDecode(prim, checker.NewConfig()); err != nil {
+				c.problems = append(c.problems, decodeProblems(err)...)
+			}
+		}
+
+		loaded[instance.name] = true
+	}
+
+	c.unknown = append(c.unknown, a.undecodedKeys(*c, loaded)...)
+}
+
+// CheckConfiguration validates the configuration file and prints its problems, it returns the exit code of the
+// --check-config mode: 1 if the file has errors and 0 otherwise. The plugins are neither initialized nor configured.
+func (a *App) CheckConfiguration() int {
+	f := ConfigFile()
+
+	c, err := a.decodeConfigFile(f)
+	if err != nil {
+		for _, problem := range decodeProblems(err) {
+			fmt.Println(problem.format(f))
+		}
+		return 1
+	}
+
+	a.checkSections(&c)
+
+	code := 0
+	for _, problem := range c.allProblems() {
+		fmt.Println(problem.format(f))
+		if !problem.warning {
+			code = 1
+		}
+	}
+
+	if code == 0 {
+		fmt.Printf("%s is valid\n", f)
+	}
+
+	return code
+}
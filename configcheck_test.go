@@ -0,0 +1,120 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyLines(t *testing.T) {
+	lines := keyLines(`# A comment = with an equals sign
+hotkey = "Alt+Space"
+
+[aliases]
+"my alias" = "github:our-org/main-repo"
+
+[plugin.github]
+token = "a=b"
+
+[[hotkeys]]
+key = "Ctrl+Alt+G"
+
+[[hotkeys]]
+key = "Ctrl+Alt+E"
+plugin = "expr"
+`)
+
+	assert.Equal(t, map[string]int{
+		"hotkey":              2,
+		"aliases":             4,
+		`aliases."my alias"`:  5,
+		"plugin.github":       7,
+		"plugin.github.token": 8,
+		"hotkeys":             10,
+		"hotkeys.0":           10,
+		"hotkeys.0.key":       11,
+		"hotkeys.key":         11,
+		"hotkeys.1":           13,
+		"hotkeys.1.key":       14,
+		"hotkeys.1.plugin":    15,
+		"hotkeys.plugin":      15,
+	}, lines)
+}
+
+func TestKeyLines_MultilineStrings(t *testing.T) {
+	lines := keyLines(`[plugin.greeter]
+template = """
+[not.a.table]
+not_a_key = 1
+"""
+literal = '''
+also_not_a_key = 2'''
+inline = """Done = on one line"""
+after = true
+`)
+
+	assert.Equal(t, 8, lines["plugin.greeter.inline"])
+	assert.Equal(t, 9, lines["plugin.greeter.after"])
+	assert.NotContains(t, lines, "not.a.table")
+	assert.NotContains(t, lines, "plugin.greeter.not_a_key")
+	assert.NotContains(t, lines, "plugin.greeter.also_not_a_key")
+}
+
+func TestKeyLines_InlineTables(t *testing.T) {
+	lines := keyLines(`[plugin.greeter]
+settings = { greeting = "hi", name = "you" }
+`)
+
+	// The keys inside an inline table are not found, their problems are reported without a line
+	assert.Equal(t, 2, lines["plugin.greeter.settings"])
+	assert.NotContains(t, lines, "plugin.greeter.settings.greeting")
+}
+
+func TestDecodeProblems(t *testing.T) {
+	var c config
+
+	_, err := toml.Decode("hotkey = \"Alt+Space\"\nplugins = [\"github\",\n\n  = 1]", &c)
+	require.Error(t, err)
+	problems := decodeProblems(err)
+	require.Len(t, problems, 1)
+	assert.Equal(t, 4, problems[0].line)
+	assert.NotContains(t, problems[0].message, "toml:")
+
+	_, err = toml.Decode("\nsuggest_timeout = \"soon\"", &c)
+	require.Error(t, err)
+	problems = decodeProblems(err)
+	require.Len(t, problems, 1)
+	assert.Equal(t, 2, problems[0].line)
+	assert.Contains(t, problems[0].message, "soon")
+}
+
+func TestCheckSections(t *testing.T) {
+	a := &App{}
+
+	c, err := a.decodeConfig(`plugins = ["github", "expr"]
+
+[plugin.github]
+token = "secret"
+tokn = "typo"
+
+[plugin.expr]
+precision = 3
+`)
+	require.NoError(t, err)
+
+	a.checkSections(&c)
+
+	// The sections are checked without starting the engine
+	assert.Nil(t, a.engine)
+
+	var messages []string
+	for _, problem := range c.allProblems() {
+		messages = append(messages, problem.String())
+	}
+	assert.Equal(t, []string{
+		`config.toml:5: warning: unknown key "plugin.github.tokn"`,
+		`config.toml:8: warning: unknown key "plugin.expr.precision"`,
+	}, messages)
+}
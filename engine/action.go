@@ -0,0 +1,100 @@
+package engine
+
+import (
+	"go-keyboard-launcher/api"
+)
+
+// ActionCopyTarget is a built-in action of File and Url items that copies their target to the clipboard
+const ActionCopyTarget = "launcher:copy-target"
+
+// Actions returns all actions of the item, the first one is its default action
+func Actions(i InternalItem) []api.Action {
+	var result []api.Action
+
+	switch i.Item.Category {
+	case api.File:
+		result = []api.Action{
+			{ID: api.DefaultAction, Label: "Open"},
+			{ID: ActionCopyTarget, Label: "Copy path"},
+		}
+	case api.Url:
+		result = []api.Action{
+			{ID: api.DefaultAction, Label: "Open in browser"},
+			{ID: ActionCopyTarget, Label: "Copy URL"},
+		}
+	default:
+		result = []api.Action{{ID: api.DefaultAction, Label: "Execute"}}
+	}
+
+	return append(result, i.Item.Actions...)
+}
+
+// ExecuteAction executes the action with the given index of the current item, index 0 being its default action. It
+// returns false if the item has no such action.
+func (e *Engine) ExecuteAction(index int) bool {
+	item, ok := e.CurrentItem()
+	if !ok || item.Item.Category == api.Error || item.action != nil {
+		return false
+	}
+
+	actions := Actions(item)
+	if index < 0 || index >= len(actions) {
+		return false
+	}
+
+	if index == 0 {
+		e.Execute()
+	} else {
+		e.execute(item, actions[index].ID, e.Query())
+	}
+
+	return true
+}
+
+// ShowActions pushes the action menu of the current item on the stack, it lists all actions of the item
+func (e *Engine) ShowActions() bool {
+	item, ok := e.CurrentItem()
+	if !ok || item.Item.Category == api.Error || item.action != nil {
+		return false
+	}
+
+	e.mutex.Lock()
+	e.itemStack = append(e.itemStack, StackEntry{
+		item:             item,
+		actions:          true,
+		searchText:       e.query,
+		suggestItems:     e.suggestItems,
+		suggestItemIndex: e.suggestItemIndex,
+	})
+	e.mutex.Unlock()
+
+	e.resetInput()
+	e.notify(EventStackChanged)
+	e.Search("")
+
+	return true
+}
+
+// actionSuggestions returns the actions of the item that match the search, in their original order
+func actionSuggestions(item InternalItem, search string) []SuggestItem {
+	var result []SuggestItem
+
+	for _, each := range Actions(item) {
+		action := each
+
+		ii := asInternalItem(api.Item{
+			ID:       "action:" + action.ID,
+			Label:    action.Label,
+			Category: api.Keyword,
+			ArgsHint: api.Forbidden,
+			Icon:     item.Item.Icon,
+		}, item.plugin)
+		ii.action = &action
+
+		if len(search) == 0 || MatchScore(search, ii.Item.Label) > 0.0 {
+			result = append(result, SuggestItem{Item: ii, Score: 1.0})
+		}
+	}
+
+	return result
+}
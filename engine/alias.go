@@ -0,0 +1,25 @@
+package engine
+
+import (
+	"strings"
+)
+
+// SetAliases configures custom words that find a specific catalog item, e.g. "mail" for the identity
+// "win32-apps:Outlook", see InternalItem.Identity. An item is found by its alias with the same score as by its label.
+// The catalog is rebuilt if it was built before.
+func (e *Engine) SetAliases(aliases map[string]string) {
+	e.mutex.Lock()
+	e.aliases = make(map[string][]string, len(aliases))
+	for alias, identity := range aliases {
+		alias = strings.TrimSpace(alias)
+		if len(alias) > 0 {
+			e.aliases[identity] = append(e.aliases[identity], alias)
+		}
+	}
+	built := e.rootIndex != nil
+	e.mutex.Unlock()
+
+	if built {
+		e.rebuildCatalog()
+	}
+}
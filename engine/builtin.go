@@ -0,0 +1,44 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os/exec"
+	"runtime"
+
+	"go-keyboard-launcher/api"
+)
+
+// executeFile opens the target of a File item with the application the OS associates with it
+func executeFile(ctx context.Context, item api.Item) error {
+	log.Printf("Launching file item %s", item)
+	return api.ShellExecuteItem(item.Target)
+}
+
+// executeUrl opens the target of a Url item in the default browser
+func executeUrl(ctx context.Context, item api.Item) error {
+	log.Printf("Launching URL item %s", item)
+
+	var cmd *exec.Cmd
+
+	switch runtime.GOOS {
+	case "linux":
+		cmd = exec.Command("xdg-open", item.Target)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", item.Target)
+	case "darwin":
+		cmd = exec.Command("open", item.Target)
+	default:
+		return fmt.Errorf("unsupported platform")
+	}
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	// The opener is not bound to the context, it may outlive the execution, but it should not become a zombie
+	go cmd.Wait()
+
+	return nil
+}
@@ -0,0 +1,148 @@
+package engine
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"time"
+
+	"go-keyboard-launcher/api"
+)
+
+// CatalogCache persists the catalog of every plugin in a directory, one file per plugin, so the launcher can show
+// the catalog right after startup instead of waiting for the plugins to build it.
+type CatalogCache struct {
+	dir string
+}
+
+// cachedItem is the part of an item that is persisted, icons are not cached
+type cachedItem struct {
+	ID          string           `json:"id,omitempty"`
+	Label       string           `json:"label"`
+	Description string           `json:"description,omitempty"`
+	Category    api.ItemCategory `json:"category"`
+	Target      string           `json:"target,omitempty"`
+	Data        interface{}      `json:"data,omitempty"`
+	ArgsHint    api.ItemArgsHint `json:"args_hint"`
+	Actions     []api.Action     `json:"actions,omitempty"`
+	Keywords    []string         `json:"keywords,omitempty"`
+	Score       float64          `json:"score,omitempty"`
+	HitHint     api.ItemHitHint  `json:"hit_hint,omitempty"`
+}
+
+type cachedCatalog struct {
+	Time  time.Time    `json:"time"` // When the plugin built the catalog
+	Items []cachedItem `json:"items"`
+}
+
+// NewCatalogCache returns a cache that stores its files in the given directory, the directory does not have to
+// exist yet
+func NewCatalogCache(dir string) *CatalogCache {
+	return &CatalogCache{dir: dir}
+}
+
+func (c *CatalogCache) file(plugin string) string {
+	return filepath.Join(c.dir, plugin+".json")
+}
+
+// Load returns the cached catalog of the plugin and the time it was built. A plugin without a cached catalog
+// returns os.ErrNotExist.
+func (c *CatalogCache) Load(plugin string) ([]api.Item, time.Time, error) {
+	data, err := os.ReadFile(c.file(plugin))
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	var cached cachedCatalog
+	if err := json.Unmarshal(data, &cached); err != nil {
+		return nil, time.Time{}, err
+	}
+
+	items := make([]api.Item, len(cached.Items))
+	for idx, each := range cached.Items {
+		items[idx] = api.Item{
+			ID:          each.ID,
+			Label:       each.Label,
+			Description: each.Description,
+			Category:    each.Category,
+			Target:      each.Target,
+			Data:        each.Data,
+			ArgsHint:    each.ArgsHint,
+			Actions:     each.Actions,
+			Keywords:    each.Keywords,
+			Score:       each.Score,
+			HitHint:     each.HitHint,
+		}
+	}
+
+	return items, cached.Time, nil
+}
+
+// Save replaces the cached catalog of the plugin. Items whose Data would not be loaded as the same value are left out,
+// see api.Item.Data.
+func (c *CatalogCache) Save(plugin string, items []api.Item, at time.Time) error {
+	cached := cachedCatalog{Time: at, Items: make([]cachedItem, 0, len(items))}
+	for _, each := range items {
+		if !roundTrips(each.Data) {
+			continue
+		}
+
+		cached.Items = append(cached.Items, cachedItem{
+			ID:          each.ID,
+			Label:       each.Label,
+			Description: each.Description,
+			Category:    each.Category,
+			Target:      each.Target,
+			Data:        each.Data,
+			ArgsHint:    each.ArgsHint,
+			Actions:     each.Actions,
+			Keywords:    each.Keywords,
+			Score:       each.Score,
+			HitHint:     each.HitHint,
+		})
+	}
+
+	data, err := json.Marshal(cached)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(c.dir, os.ModePerm); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so a crash cannot leave a truncated catalog behind
+	tmp := c.file(plugin) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, c.file(plugin))
+}
+
+// roundTrips returns true if the data decodes from its JSON encoding to the same value, e.g. a struct decodes to a
+// map and an int to a float64
+func roundTrips(data interface{}) bool {
+	if data == nil {
+		return true
+	}
+
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return false
+	}
+
+	var decoded interface{}
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		return false
+	}
+
+	return reflect.DeepEqual(data, decoded)
+}
+
+// isNotCached returns true if the error of Load means there is no cached catalog
+func isNotCached(err error) bool {
+	return errors.Is(err, os.ErrNotExist)
+}
@@ -0,0 +1,220 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go-keyboard-launcher/api"
+)
+
+// catalogRetryInterval is how long a plugin waits before it tries again after failing to build its catalog, unless
+// its refresh interval is shorter
+const catalogRetryInterval = time.Minute
+
+// SetCatalogCache sets the cache the catalogs of the plugins are loaded from and saved to
+func (e *Engine) SetCatalogCache(c *CatalogCache) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.cache = c
+}
+
+// LoadCatalog loads the cached catalogs of the plugins and rebuilds the root items from them, it does not ask the
+// plugins to build their catalogs
+func (e *Engine) LoadCatalog() {
+	e.mutex.Lock()
+	cache := e.cache
+	e.mutex.Unlock()
+
+	if cache == nil {
+		return
+	}
+
+	for _, p := range e.plugins {
+		items, at, err := cache.Load(p.Name())
+		if isNotCached(err) {
+			continue
+		} else if err != nil {
+			e.log.Error(fmt.Sprintf("Failed to load cached catalog of plugin %s", p.Name()), "error", err)
+			continue
+		}
+
+		e.setCatalog(p, items, at)
+	}
+
+	e.rebuildCatalog()
+}
+
+// StartCatalogRefresh rebuilds the catalog of every plugin in the background, whenever its refresh interval has
+// passed since the catalog was last built. Cached catalogs that are older than the interval are refreshed right
+// away. The refresh stops when the context is done.
+func (e *Engine) StartCatalogRefresh(ctx context.Context) {
+	for _, p := range e.plugins {
+		go e.refreshLoop(ctx, p)
+	}
+}
+
+func (e *Engine) refreshLoop(ctx context.Context, p api.Plugin) {
+	interval := e.optionsOf(p).RefreshInterval
+
+	e.mutex.Lock()
+	last := e.catalogTimes[p.Name()]
+	e.mutex.Unlock()
+
+	wait := time.Until(last.Add(interval))
+
+	for {
+		if wait > 0 {
+			timer := time.NewTimer(wait)
+
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+		}
+
+		if e.refreshCatalog(p) != nil && catalogRetryInterval < interval {
+			wait = catalogRetryInterval
+		} else {
+			wait = interval
+		}
+	}
+}
+
+// RefreshCatalog lets the plugin build its catalog again right away, e.g. after its configuration changed
+func (e *Engine) RefreshCatalog(p api.Plugin) error {
+	return e.refreshCatalog(p)
+}
+
+// refreshCatalog lets the plugin build its catalog and swaps it into the root items. A plugin without a catalog
+// shows its items as soon as they arrive, otherwise its previous catalog is shown until the new one is complete. When
+// the plugin fails the items it delivered so far are kept, together with the previous items it did not deliver again.
+// A refresh waits for the previous refresh of the plugin.
+func (e *Engine) refreshCatalog(p api.Plugin) error {
+	lock := e.pluginMutexes[p.Name()]
+	lock.Lock()
+	defer lock.Unlock()
+
+	e.mutex.Lock()
+	previous, hasCatalog := e.catalogs[p.Name()]
+	previousTime := e.catalogTimes[p.Name()]
+	cache := e.cache
+	e.catalogProgress[p.Name()] = 0
+	e.mutex.Unlock()
+
+	e.notify(EventCatalogChanged)
+
+	var mutex sync.Mutex
+	var items []api.Item
+	done := false
+
+	err := e.catalogPlugin(p, func(batch []api.Item) {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		if done {
+			return
+		}
+
+		items = append(items, batch...)
+
+		e.mutex.Lock()
+		e.catalogProgress[p.Name()] = len(items)
+		e.mutex.Unlock()
+
+		if !hasCatalog {
+			e.setCatalog(p, items, time.Time{})
+			e.rebuildCatalog()
+		}
+
+		e.notify(EventCatalogChanged)
+	})
+
+	// Batches that arrive from now on are dropped
+	mutex.Lock()
+	done = true
+	found := items
+	mutex.Unlock()
+
+	e.mutex.Lock()
+	e.catalogErrors[p.Name()] = err
+	delete(e.catalogProgress, p.Name())
+	e.mutex.Unlock()
+
+	if err == errCatalogCancelled {
+		// The catalog is built again with the new configuration, until then the previous one stays
+		e.log.Info(fmt.Sprintf("Cancelled the catalog of plugin %s", p.Name()), "reason", err)
+		e.notify(EventCatalogChanged)
+		return err
+	} else if err != nil {
+		e.log.Error(fmt.Sprintf("Failed to catalog plugin %s", p.Name()), "error", err)
+
+		if len(found) > 0 {
+			e.setCatalog(p, mergeItems(found, previous), previousTime)
+			e.rebuildCatalog()
+		}
+
+		e.notify(EventCatalogChanged)
+		return err
+	}
+
+	now := time.Now()
+	e.setCatalog(p, found, now)
+
+	if cache != nil {
+		if err := cache.Save(p.Name(), found, now); err != nil {
+			e.log.Error(fmt.Sprintf("Failed to cache catalog of plugin %s", p.Name()), "error", err)
+		}
+	}
+
+	e.rebuildCatalog()
+	e.notify(EventCatalogChanged)
+
+	return nil
+}
+
+// CatalogProgress returns the number of items found so far by each plugin that is building its catalog
+func (e *Engine) CatalogProgress() map[string]int {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	result := make(map[string]int, len(e.catalogProgress))
+	for name, count := range e.catalogProgress {
+		result[name] = count
+	}
+
+	return result
+}
+
+// mergeItems returns the found items followed by the previous items that were not found again
+func mergeItems(found []api.Item, previous []InternalItem) []api.Item {
+	keys := make(map[string]bool, len(found))
+	for _, each := range found {
+		keys[each.Key()] = true
+	}
+
+	result := append([]api.Item(nil), found...)
+	for _, each := range previous {
+		if !keys[each.Item.Key()] {
+			result = append(result, each.Item)
+		}
+	}
+
+	return result
+}
+
+func (e *Engine) setCatalog(p api.Plugin, items []api.Item, at time.Time) {
+	catalog := make([]InternalItem, len(items))
+	for idx, each := range items {
+		catalog[idx] = asInternalItem(each, p)
+	}
+
+	e.mutex.Lock()
+	e.catalogs[p.Name()] = catalog
+	e.catalogTimes[p.Name()] = at
+	e.mutex.Unlock()
+}
@@ -0,0 +1,171 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go-keyboard-launcher/api"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCatalogCache_RoundTrip(t *testing.T) {
+	c := NewCatalogCache(t.TempDir())
+
+	_, _, err := c.Load("github")
+	assert.True(t, isNotCached(err))
+
+	at := time.Now().Add(-time.Hour).Round(time.Second)
+	items := []api.Item{{ID: "repo", Label: "Repository", Category: api.Url, Target: "https://github.com", Data: "repo"}}
+	assert.NoError(t, c.Save("github", items, at))
+
+	loaded, loadedAt, err := c.Load("github")
+	assert.NoError(t, err)
+	assert.Equal(t, items, loaded)
+	assert.True(t, at.Equal(loadedAt))
+}
+
+func TestCatalogCache_DataThatDoesNotRoundTrip(t *testing.T) {
+	c := NewCatalogCache(t.TempDir())
+
+	type pull struct{ Number int }
+	items := []api.Item{
+		{Label: "Text", Category: api.User, Data: "text"},
+		{Label: "Struct", Category: api.User, Data: pull{Number: 1}},
+		{Label: "Integer", Category: api.User, Data: 1},
+		{Label: "Decoded", Category: api.User, Data: map[string]interface{}{"number": 1.0}},
+	}
+	assert.NoError(t, c.Save("github", items, time.Now()))
+
+	// Items that would come back with Data of another type are not cached
+	loaded, _, err := c.Load("github")
+	assert.NoError(t, err)
+	assert.Equal(t, []api.Item{items[0], items[3]}, loaded)
+}
+
+func TestEngine_CachedCatalog(t *testing.T) {
+	cache := NewCatalogCache(t.TempDir())
+	assert.NoError(t, cache.Save("test", []api.Item{{Label: "Cached", Category: api.User}}, time.Now()))
+
+	fail := true
+	p := &catalogPlugin{testPlugin: *newTestPlugin(), catalog: func(ctx context.Context, callback api.CatalogCallback) error {
+		if fail {
+			return errors.New("offline")
+		}
+		callback([]api.Item{{Label: "Repository", Category: api.User}})
+		callback([]api.Item{{Label: "Encode", Category: api.User}, {Label: "Other", Category: api.User}})
+		return nil
+	}}
+
+	e := New(hclog.NewNullLogger(), []api.Plugin{p})
+	e.SetCatalogCache(cache)
+	e.LoadCatalog()
+
+	e.Search("cached")
+	waitForLabels(t, e, "Cached")
+
+	// A failed refresh keeps the cached catalog
+	e.Catalog()
+	e.Search("cached")
+	waitForLabels(t, e, "Cached", "test: offline")
+
+	// A successful refresh replaces it and is cached
+	fail = false
+	e.Catalog()
+	e.Search("repo")
+	waitForLabels(t, e, "Repository")
+
+	items, _, err := cache.Load("test")
+	assert.NoError(t, err)
+	assert.Len(t, items, 3)
+}
+
+func TestEngine_CatalogRefresh(t *testing.T) {
+	refreshes := make(chan time.Time, 10)
+	p := &catalogPlugin{testPlugin: *newTestPlugin(), catalog: func(ctx context.Context, callback api.CatalogCallback) error {
+		select {
+		case refreshes <- time.Now():
+		default:
+		}
+		callback([]api.Item{{Label: "Repository", Category: api.User}})
+		return nil
+	}}
+
+	saved := time.Now()
+	cache := NewCatalogCache(t.TempDir())
+	assert.NoError(t, cache.Save("test", nil, saved))
+
+	e := New(hclog.NewNullLogger(), []api.Plugin{p})
+	e.SetCatalogCache(cache)
+	e.SetPluginOptions("test", PluginOptions{RefreshInterval: 50 * time.Millisecond})
+	e.LoadCatalog()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	e.StartCatalogRefresh(ctx)
+
+	// The cached catalog is fresh, so the first refresh waits for the interval
+	for i := 0; i < 2; i++ {
+		select {
+		case at := <-refreshes:
+			if i == 0 {
+				assert.GreaterOrEqual(t, at.Sub(saved), 50*time.Millisecond)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("only %d refreshes", i)
+		}
+	}
+
+	cancel()
+	e.Search("repo")
+	waitForLabels(t, e, "Repository")
+}
+
+func TestEngine_IncrementalCatalog(t *testing.T) {
+	page := make(chan []api.Item)
+	p := &catalogPlugin{testPlugin: *newTestPlugin(), catalog: func(ctx context.Context, callback api.CatalogCallback) error {
+		for batch := range page {
+			callback(batch)
+		}
+		return errors.New("rate limited")
+	}}
+
+	e := New(hclog.NewNullLogger(), []api.Plugin{p})
+	done := make(chan bool)
+	go func() {
+		e.Catalog()
+		done <- true
+	}()
+
+	// Without a previous catalog every batch is searchable right away
+	page <- []api.Item{{ID: "1", Label: "First", Category: api.User}}
+	e.Search("first")
+	waitForLabels(t, e, "First")
+	assert.Equal(t, map[string]int{"test": 1}, e.CatalogProgress())
+
+	page <- []api.Item{{ID: "2", Label: "Second", Category: api.User}}
+	assert.Eventually(t, func() bool {
+		return e.CatalogProgress()["test"] == 2
+	}, time.Second, time.Millisecond)
+
+	// A failure keeps the partial catalog
+	close(page)
+	<-done
+	assert.Empty(t, e.CatalogProgress())
+
+	e.Search("second")
+	waitForLabels(t, e, "Second", "test: rate limited")
+}
+
+func TestMergeItems(t *testing.T) {
+	p := newTestPlugin()
+	previous := []InternalItem{
+		asInternalItem(api.Item{ID: "a", Label: "Old A"}, p),
+		asInternalItem(api.Item{ID: "b", Label: "Old B"}, p),
+	}
+
+	merged := mergeItems([]api.Item{{ID: "a", Label: "New A"}}, previous)
+	assert.Equal(t, []api.Item{{ID: "a", Label: "New A"}, {ID: "b", Label: "Old B"}}, merged)
+}
@@ -0,0 +1,834 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"go-keyboard-launcher/api"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+// Event is emitted by the engine to its listeners whenever its state changes
+type Event int
+
+const (
+	EventSuggestionsChanged Event = iota
+	EventStackChanged
+	EventDismissed // An item was executed or the user cancelled at the root, the frontend should hide itself
+	EventCatalogChanged
+	EventFailed // An execution failed, the frontend should show itself to report it even if it was hidden
+)
+
+type Listener func(Event)
+
+// Engine holds the search state of the launcher: the catalog, the current query, the suggested items and the stack
+// of items that were pushed while navigating. It is independent of any user interface, a frontend drives it through
+// its methods and renders its state whenever it is notified of a change.
+type Engine struct {
+	log     hclog.Logger
+	plugins []api.Plugin
+	history *History
+
+	pluginOptions map[string]PluginOptions
+	catalogErrors map[string]error    // The last error of each plugin while building the catalog
+	prefixes      map[string]string   // Search keywords and the plugin or item they route a query to
+	aliases       map[string][]string // The words the user configured for items, by the identity of the item
+
+	// The catalog of each plugin and when it was built, guarded by mutex. Rebuilding the root items from them is
+	// serialized by rebuildMutex.
+	cache           *CatalogCache
+	catalogs        map[string][]InternalItem
+	catalogTimes    map[string]time.Time
+	catalogProgress map[string]int                // Number of items found so far by the plugins that are building their catalog
+	cataloging      map[string]bool               // The plugins whose Catalog has not returned yet, even if it timed out
+	catalogCancels  map[string]context.CancelFunc // Cancel the catalogs that are being built, by plugin
+	rebuildMutex    sync.Mutex
+
+	// The refreshes of the catalog of a plugin take turns, by the name of the plugin
+	pluginMutexes map[string]*sync.Mutex
+
+	// Item state, guarded by mutex
+	mutex            sync.Mutex
+	rootIndex        *catalogIndex
+	query            string
+	suggestItems     []SuggestItem
+	suggestItemIndex int
+	itemStack        []StackEntry
+
+	// Every search gets a new generation, results of plugins for an older generation are dropped
+	generation           uint64
+	lastSearchCancelFunc *context.CancelFunc
+	listeners            []Listener
+}
+
+func New(log hclog.Logger, plugins []api.Plugin) *Engine {
+	e := Engine{
+		log:              log,
+		plugins:          plugins,
+		history:          NewHistory(),
+		pluginOptions:    make(map[string]PluginOptions),
+		catalogErrors:    make(map[string]error),
+		catalogs:         make(map[string][]InternalItem),
+		catalogTimes:     make(map[string]time.Time),
+		catalogProgress:  make(map[string]int),
+		cataloging:       make(map[string]bool),
+		catalogCancels:   make(map[string]context.CancelFunc),
+		pluginMutexes:    make(map[string]*sync.Mutex, len(plugins)),
+		suggestItemIndex: -1,
+	}
+
+	// Initialize the plugins
+	for _, p := range plugins {
+		p.Initialize(log.Named(p.Name()))
+		e.pluginMutexes[p.Name()] = &sync.Mutex{}
+	}
+
+	return &e
+}
+
+// Subscribe registers a listener that is invoked on every state change. Listeners may be invoked from any goroutine,
+// so they should not block.
+func (e *Engine) Subscribe(l Listener) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.listeners = append(e.listeners, l)
+}
+
+func (e *Engine) notify(evt Event) {
+	e.mutex.Lock()
+	listeners := e.listeners
+	e.mutex.Unlock()
+
+	for _, l := range listeners {
+		l(evt)
+	}
+}
+
+// SetHistory replaces the usage history that is used to rank suggestions and that executions are recorded in
+func (e *Engine) SetHistory(h *History) {
+	e.history = h
+}
+
+func (e *Engine) History() *History {
+	return e.history
+}
+
+func (e *Engine) Plugins() []api.Plugin {
+	return e.plugins
+}
+
+func (e *Engine) PluginByName(k string) (api.Plugin, bool) {
+	for _, each := range e.plugins {
+		if each.Name() == k {
+			return each, true
+		}
+	}
+
+	return nil, false
+}
+
+// Catalog lets every plugin build its catalog and waits until all of them are done
+func (e *Engine) Catalog() {
+	var wg sync.WaitGroup
+
+	for _, p := range e.plugins {
+		wg.Add(1)
+
+		go func(p api.Plugin) {
+			defer wg.Done()
+			e.refreshCatalog(p)
+		}(p)
+	}
+
+	wg.Wait()
+}
+
+func (e *Engine) catalog() []InternalItem {
+	return e.catalogIndex().items
+}
+
+func (e *Engine) catalogIndex() *catalogIndex {
+	e.mutex.Lock()
+	index := e.rootIndex
+	e.mutex.Unlock()
+
+	if index == nil {
+		index = e.rebuildCatalog()
+	}
+
+	return index
+}
+
+// rebuildCatalog combines the catalogs of all plugins into the root items and indexes them
+func (e *Engine) rebuildCatalog() *catalogIndex {
+	e.rebuildMutex.Lock()
+	defer e.rebuildMutex.Unlock()
+
+	catalog := make([]InternalItem, 0)
+
+	e.mutex.Lock()
+	for _, plugin := range e.plugins {
+		catalog = append(catalog, e.catalogs[plugin.Name()]...)
+	}
+
+	for idx, each := range catalog {
+		catalog[idx].aliases = e.aliases[each.Identity()]
+	}
+	e.mutex.Unlock()
+
+	index := newCatalogIndex(catalog)
+
+	log.Println("Catalog rebuild")
+
+	// The new catalog replaces the old one at once, searches never see a partial catalog
+	e.mutex.Lock()
+	e.rootIndex = index
+	e.mutex.Unlock()
+
+	return index
+}
+
+// Query returns the text that was last searched for
+func (e *Engine) Query() string {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	return e.query
+}
+
+// Suggestions returns a snapshot of the currently suggested items, ordered by descending priority and score
+func (e *Engine) Suggestions() []SuggestItem {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	result := make([]SuggestItem, len(e.suggestItems))
+	copy(result, e.suggestItems)
+	return result
+}
+
+// SelectedIndex returns the index of the selected suggestion, or -1 if nothing is selected
+func (e *Engine) SelectedIndex() int {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	return e.suggestItemIndex
+}
+
+// Stack returns a snapshot of the items that were pushed, the first entry is the root
+func (e *Engine) Stack() []StackEntry {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	result := make([]StackEntry, len(e.itemStack))
+	copy(result, e.itemStack)
+	return result
+}
+
+func (e *Engine) HasCurrentItem() bool {
+	_, ok := e.CurrentItem()
+	return ok
+}
+
+func (e *Engine) CurrentItem() (InternalItem, bool) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if e.suggestItemIndex < 0 || e.suggestItemIndex >= len(e.suggestItems) {
+		return InternalItem{}, false
+	}
+
+	return e.suggestItems[e.suggestItemIndex].Item, true
+}
+
+// Select makes the suggestion at the given index the current item, it returns false if the index is out of range
+func (e *Engine) Select(index int) bool {
+	e.mutex.Lock()
+
+	if index < 0 || index >= len(e.suggestItems) {
+		e.mutex.Unlock()
+		return false
+	}
+
+	e.suggestItemIndex = index
+	e.mutex.Unlock()
+
+	e.notify(EventSuggestionsChanged)
+	return true
+}
+
+func (e *Engine) SelectNext() bool {
+	return e.Select(e.SelectedIndex() + 1)
+}
+
+func (e *Engine) SelectPrevious() bool {
+	return e.Select(e.SelectedIndex() - 1)
+}
+
+// Push pushes the current item on the stack so the next searches are handled by its plugin
+func (e *Engine) Push() bool {
+	item, ok := e.CurrentItem()
+	if !ok || item.Item.ArgsHint == api.Forbidden {
+		return false
+	}
+
+	e.push(item)
+	e.Search("")
+
+	return true
+}
+
+func (e *Engine) push(item InternalItem) {
+	e.mutex.Lock()
+	e.itemStack = append(e.itemStack, StackEntry{
+		item:             item,
+		searchText:       e.query,
+		suggestItems:     e.suggestItems,
+		suggestItemIndex: e.suggestItemIndex,
+	})
+	e.mutex.Unlock()
+
+	e.resetInput()
+	e.notify(EventStackChanged)
+}
+
+// Pop removes the top of the stack and restores the query and suggestions from before it was pushed
+func (e *Engine) Pop() bool {
+	e.cancelLastSearch()
+
+	e.mutex.Lock()
+	if len(e.itemStack) == 0 {
+		e.mutex.Unlock()
+		return false
+	}
+
+	top := e.itemStack[len(e.itemStack)-1]
+	e.itemStack = e.itemStack[:len(e.itemStack)-1]
+
+	// Restore the last search
+	e.query = top.searchText
+	e.suggestItems = top.suggestItems
+	e.suggestItemIndex = top.suggestItemIndex
+	e.mutex.Unlock()
+
+	e.notify(EventStackChanged)
+	e.notify(EventSuggestionsChanged)
+
+	return true
+}
+
+// Cancel clears the query if there is one, otherwise it pops the stack. When there is nothing left to cancel the
+// listeners are told to dismiss the frontend.
+func (e *Engine) Cancel() {
+	if len(e.Query()) > 0 {
+		e.resetInput()
+	} else if !e.Pop() {
+		e.notify(EventDismissed)
+	}
+}
+
+// Reset empties the stack and the query
+func (e *Engine) Reset() {
+	e.mutex.Lock()
+	e.itemStack = nil
+	e.mutex.Unlock()
+
+	e.resetInput()
+	e.notify(EventStackChanged)
+}
+
+// Open starts over with the search limited to the plugin, or with all plugins if the plugin is empty, and searches
+// the query as if it was typed, so it may start with a prefix keyword
+func (e *Engine) Open(plugin string, query string) error {
+	var scope InternalItem
+	if len(plugin) > 0 {
+		p, ok := e.PluginByName(plugin)
+		if !ok {
+			return fmt.Errorf("unknown plugin %q", plugin)
+		}
+		scope = scopeItem(p)
+	}
+
+	e.Reset()
+
+	if scope.plugin != nil {
+		e.mutex.Lock()
+		e.itemStack = append(e.itemStack, StackEntry{item: scope, scope: true})
+		e.mutex.Unlock()
+
+		e.notify(EventStackChanged)
+	}
+
+	e.Search(query)
+
+	return nil
+}
+
+// ExecuteIdentity starts over and executes the default action of the catalog item with the identity, e.g.
+// "str:base64". An item that requires an argument is pushed on the stack instead, it returns true in that case so the
+// frontend can be shown to enter the argument.
+func (e *Engine) ExecuteIdentity(identity string) (bool, error) {
+	item, ok := e.catalogItem(identity)
+	if !ok {
+		return false, fmt.Errorf("unknown item %q", identity)
+	}
+
+	e.Reset()
+
+	if item.Item.ArgsHint == api.Required {
+		e.push(item)
+		return true, nil
+	}
+
+	e.execute(item, api.DefaultAction, "")
+
+	return false, nil
+}
+
+// OpenItem starts over with the catalog item with the identity pushed on the stack, and opens the suggestion with the
+// ID subItem as soon as the plugin suggests it, e.g. the pull requests of a repository. The suggestion is pushed if
+// it can be, otherwise it is executed.
+func (e *Engine) OpenItem(identity string, subItem string) error {
+	item, ok := e.catalogItem(identity)
+	if !ok {
+		return fmt.Errorf("unknown item %q", identity)
+	} else if item.Item.ArgsHint == api.Forbidden {
+		return fmt.Errorf("item %q has no suggestions to open %q from", identity, subItem)
+	}
+
+	e.Reset()
+	e.push(item)
+
+	e.mutex.Lock()
+	e.itemStack[len(e.itemStack)-1].open = subItem
+	e.mutex.Unlock()
+
+	e.Search("")
+
+	return nil
+}
+
+// openPending opens the suggestion the top of the stack waits for, see OpenItem. Once the user typed a query it is
+// no longer opened.
+func (e *Engine) openPending() {
+	e.mutex.Lock()
+	if len(e.itemStack) == 0 || len(e.itemStack[len(e.itemStack)-1].open) == 0 || len(e.query) > 0 {
+		e.mutex.Unlock()
+		return
+	}
+
+	top := &e.itemStack[len(e.itemStack)-1]
+	index := -1
+	for idx, each := range e.suggestItems {
+		if each.Item.plugin == top.item.plugin && each.Item.Item.ID == top.open {
+			index = idx
+			break
+		}
+	}
+
+	if index < 0 {
+		// The plugin may deliver it with a later batch
+		e.mutex.Unlock()
+		return
+	}
+
+	top.open = ""
+	e.suggestItemIndex = index
+	forbidden := e.suggestItems[index].Item.Item.ArgsHint == api.Forbidden
+	e.mutex.Unlock()
+
+	if forbidden {
+		e.Execute()
+	} else {
+		e.Push()
+	}
+}
+
+// ShowFailure starts over with the error as the only suggestion, it reports failures of the frontend the way failed
+// executions are reported, e.g. a hotkey whose item does not exist
+func (e *Engine) ShowFailure(source string, err error) {
+	e.Reset()
+
+	e.mutex.Lock()
+	generation := e.generation
+	e.mutex.Unlock()
+
+	e.addSuggestions(generation, []SuggestItem{{
+		Item:     InternalItem{Item: api.NewErrorItem(source, err)},
+		Priority: priorityFailure,
+	}})
+}
+
+func (e *Engine) resetInput() {
+	e.cancelLastSearch()
+	e.clearSuggestions()
+
+	e.mutex.Lock()
+	e.query = ""
+	e.mutex.Unlock()
+}
+
+// Execute executes the default action of the current item, or pushes it on the stack when the item requires an
+// argument. In the action menu of an item it executes the selected action.
+func (e *Engine) Execute() {
+	item, ok := e.CurrentItem()
+	if !ok {
+		return
+	}
+
+	if item.Item.Category == api.Error {
+		// Errors are only shown, there is nothing to execute
+		return
+	}
+
+	if item.action != nil {
+		// The item of the menu is executed, as if it was done with the query that led to it
+		stack := e.Stack()
+		top := stack[len(stack)-1]
+		e.execute(top.item, item.action.ID, top.searchText)
+		return
+	}
+
+	if item.Item.ArgsHint == api.Required {
+		e.push(item)
+		return
+	}
+
+	e.execute(item, api.DefaultAction, e.Query())
+}
+
+// execute executes the action of the item in the background and records it in the history
+func (e *Engine) execute(item InternalItem, action string, query string) {
+	if err := e.history.Record(item.Identity(), query); err != nil {
+		e.log.Error("Failed to record history", "error", err)
+	}
+
+	e.mutex.Lock()
+	generation := e.generation
+	e.mutex.Unlock()
+
+	e.log.Debug("Executing item", "item", item.DisplayName(), "action", action)
+
+	// The frontend is dismissed once the item executed successfully, a failure is shown on top of the suggestions
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), e.optionsOf(item.plugin).ExecuteTimeout)
+		defer cancel()
+
+		if err := e.executeItem(ctx, item, action); err != nil {
+			e.log.Error(fmt.Sprintf("Failed to execute %s", item.Item), "error", err)
+
+			failure := errorSuggestion(item.plugin, err)
+			failure.Priority = priorityFailure
+			e.addSuggestions(generation, []SuggestItem{failure})
+			e.notify(EventFailed)
+			return
+		}
+
+		e.notify(EventDismissed)
+	}()
+}
+
+func (e *Engine) Search(input string) {
+	if rest, ok := e.routePrefix(input); ok {
+		e.Search(rest)
+		return
+	}
+
+	e.cancelLastSearch()
+
+	// Remove whitespace and lower for search matching
+	search := strings.ToLower(strings.Trim(input, " "))
+
+	e.mutex.Lock()
+	e.query = input
+	stack := e.itemStack
+	generation := e.generation
+
+	// A new query starts with the best suggestion selected
+	e.suggestItemIndex = -1
+	e.mutex.Unlock()
+
+	// The action menu of an item only filters its actions
+	if len(stack) > 0 && stack[len(stack)-1].actions {
+		e.setSuggestions(generation, actionSuggestions(stack[len(stack)-1].item, search))
+		return
+	}
+
+	// A scope limits the search to a single plugin, otherwise the search starts at the root
+	var scope api.Plugin
+	if len(stack) > 0 && stack[0].scope {
+		scope = stack[0].item.plugin
+		stack = stack[1:]
+	}
+
+	// If there is no search query at the root empty the list of suggestItems, we're done
+	if len(stack) == 0 && len(search) == 0 {
+		e.clearSuggestions()
+		return
+	}
+
+	// Dispatch search to plugins
+	ctx, cancel := context.WithCancel(context.Background())
+
+	e.mutex.Lock()
+	e.lastSearchCancelFunc = &cancel
+	e.mutex.Unlock()
+
+	// Let the plugins do some suggestItems
+	if len(stack) > 0 {
+		p := stack[0].item.plugin
+		chain := collectItems(stack)
+
+		// The original user input is passed
+		go e.suggest(ctx, generation, p, input, chain, func(items []api.Item, match api.Match) {
+			internalItems := e.createSuggestions(items, match, p, search)
+			e.setSuggestions(generation, internalItems)
+		})
+
+	} else {
+		// Broadcast the search query to all plugins, or only to the plugin of the scope
+		plugins := e.plugins
+		if scope != nil {
+			plugins = []api.Plugin{scope}
+		}
+
+		// Matching a large catalog takes a while, so it is done in the background. The items that match directly
+		// replace the previous suggestions before the plugins add theirs.
+		go func() {
+			suggestions := e.catalogIndex().search(e, scope, search)
+			suggestions = append(suggestions, e.catalogErrorSuggestions(scope)...)
+			e.setSuggestions(generation, suggestions)
+
+			for _, p := range plugins {
+				p := p
+				go e.suggest(ctx, generation, p, input, nil, func(items []api.Item, match api.Match) {
+					internalItems := e.createSuggestions(items, match, p, search)
+					e.addSuggestions(generation, internalItems)
+				})
+			}
+		}()
+	}
+}
+
+// catalogErrorSuggestions returns an error item for every plugin that failed to build its catalog, limited to the
+// plugin of the scope if there is one
+func (e *Engine) catalogErrorSuggestions(scope api.Plugin) []SuggestItem {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	var result []SuggestItem
+	for _, p := range e.plugins {
+		if scope != nil && p != scope {
+			continue
+		}
+
+		if err := e.catalogErrors[p.Name()]; err != nil {
+			result = append(result, errorSuggestion(p, err))
+		}
+	}
+
+	return result
+}
+
+func (e *Engine) createSuggestions(items []api.Item, match api.Match, p api.Plugin, search string) []SuggestItem {
+	history := e.history.Scores(search)
+
+	var internalItems []SuggestItem
+	for idx, i := range items {
+		if s, ok := e.suggestion(asInternalItem(i, p), match, search, history, idx, len(items)); ok {
+			internalItems = append(internalItems, s)
+		}
+	}
+
+	return internalItems
+}
+
+// suggestion scores the item for the search according to the match mode and the hints of the item, it returns false
+// if the item does not match. The index and count give the position of the item among the plugin's results.
+func (e *Engine) suggestion(ii InternalItem, match api.Match, search string, history HistoryScores, index int, count int) (SuggestItem, bool) {
+	score := 1.0
+	var ranges []Range
+
+	if len(search) > 0 {
+		switch match {
+		case api.MatchFuzzy, api.MatchOrdered:
+			score, ranges = newMatcher(search).matchItem(ii)
+		case api.MatchPrefix:
+			score = PrefixScore(search, ii.lookupName)
+			ranges = []Range{{Start: 0, End: utf8.RuneCountInString(search)}}
+		case api.MatchWord:
+			score = WordScore(search, ii.lookupName)
+		}
+
+		if score == 0.0 {
+			return SuggestItem{}, false
+		}
+	}
+
+	return e.ranked(ii, match, history, score, ranges, index, count), true
+}
+
+// ranked returns the suggestion of an item that matched the search with the given score and ranges, adjusted by the
+// score of the plugin, the history of the search and the hit hint
+func (e *Engine) ranked(ii InternalItem, match api.Match, history HistoryScores, score float64, ranges []Range, index int, count int) SuggestItem {
+	return e.rankedWith(ii, match, history, score, ranges, index, count, e.optionsOf(ii.plugin).Priority)
+}
+
+// rankedWith is ranked with the priority of the plugin of the item, for callers that rank many items at once
+func (e *Engine) rankedWith(ii InternalItem, match api.Match, history HistoryScores, score float64, ranges []Range, index int, count int, pluginPriority int) SuggestItem {
+	if ii.Item.Score > 0.0 {
+		// The plugin knows best how relevant the item is
+		score = ii.Item.Score
+	}
+
+	if match == api.MatchOrdered {
+		// Rank by position, so that the history cannot change the order of the plugin
+		score = 1.0 - float64(index)/float64(count)
+	} else if !history.empty() {
+		score += history.Bonus(ii.Identity())
+	}
+
+	score, priority := hinted(&ii.Item, score)
+
+	return SuggestItem{
+		Item:           ii,
+		Score:          score,
+		Priority:       priority,
+		Ranges:         ranges,
+		pluginPriority: pluginPriority,
+	}
+}
+
+// hinted returns the score and the priority of the item according to its hit hint
+func hinted(item *api.Item, score float64) (float64, int) {
+	switch item.HitHint {
+	case api.HitBoost:
+		return score + hitBoost, priorityNormal
+	case api.HitTop:
+		return score, priorityTop
+	}
+
+	return score, priorityNormal
+}
+
+// cancelLastSearch cancels the context of the running search and starts a new generation, so that results that
+// plugins still deliver for it are ignored, even when they do not honour the context
+func (e *Engine) cancelLastSearch() {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.generation++
+
+	if e.lastSearchCancelFunc != nil {
+		// Cancel last search request
+		(*e.lastSearchCancelFunc)()
+		e.lastSearchCancelFunc = nil
+	}
+}
+
+func (e *Engine) clearSuggestions() {
+	log.Println("clearSuggestions()")
+
+	e.mutex.Lock()
+	e.suggestItems = nil
+	e.suggestItemIndex = -1
+	e.mutex.Unlock()
+
+	e.notify(EventSuggestionsChanged)
+}
+
+func (e *Engine) setSuggestions(generation uint64, suggestions []SuggestItem) {
+	log.Println("settingSuggestions(", len(suggestions), ")")
+
+	e.mutex.Lock()
+	if generation != e.generation {
+		e.mutex.Unlock()
+		e.log.Debug("Dropping suggestions of a superseded search", "suggestions", len(suggestions))
+		return
+	}
+
+	e.replaceSuggestions(suggestions)
+	e.mutex.Unlock()
+
+	e.notify(EventSuggestionsChanged)
+	e.openPending()
+}
+
+func (e *Engine) addSuggestions(generation uint64, suggestions []SuggestItem) {
+	log.Println("addSuggestions(", len(suggestions), ")")
+	if len(suggestions) == 0 {
+		return
+	}
+
+	e.mutex.Lock()
+	if generation != e.generation {
+		e.mutex.Unlock()
+		e.log.Debug("Dropping suggestions of a superseded search", "suggestions", len(suggestions))
+		return
+	}
+
+	var result []SuggestItem
+
+	for _, each := range suggestions {
+		result = append(result, each)
+	}
+	for _, each := range e.suggestItems {
+		result = append(result, each)
+	}
+
+	e.replaceSuggestions(result)
+	e.mutex.Unlock()
+
+	e.notify(EventSuggestionsChanged)
+	e.openPending()
+}
+
+// replaceSuggestions sorts and de-duplicates the suggestions and makes them the current ones. The selected item
+// remains selected if it is still suggested, otherwise the first item is selected. Must be called with the mutex held.
+func (e *Engine) replaceSuggestions(suggestions []SuggestItem) {
+	selected := ""
+	if e.suggestItemIndex >= 0 && e.suggestItemIndex < len(e.suggestItems) {
+		selected = e.suggestItems[e.suggestItemIndex].Item.Identity()
+	}
+
+	// Sort items
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		return ranksAbove(suggestions[i], suggestions[j])
+	})
+
+	// Only keep the best scoring suggestion of an item
+	seen := make(map[string]bool, len(suggestions))
+	result := suggestions[:0]
+
+	for _, each := range suggestions {
+		id := each.Item.Identity()
+		if seen[id] {
+			continue
+		}
+
+		seen[id] = true
+		result = append(result, each)
+	}
+
+	e.suggestItems = result
+	e.suggestItemIndex = -1
+
+	for idx, each := range result {
+		if each.Item.Identity() == selected {
+			e.suggestItemIndex = idx
+			break
+		}
+	}
+
+	// If we have any results, select the first item
+	if e.suggestItemIndex == -1 && len(result) > 0 {
+		e.suggestItemIndex = 0
+	}
+}
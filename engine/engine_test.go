@@ -0,0 +1,951 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"image"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go-keyboard-launcher/api"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+)
+
+type testPlugin struct {
+	name     string
+	items    []api.Item
+	suggest  func(input string, chain []api.Item) []api.Item
+	execute  func(item api.Item) error
+	executed chan execution
+}
+
+type execution struct {
+	item   api.Item
+	action string
+}
+
+func (p *testPlugin) Initialize(hclog.Logger)            {}
+func (p *testPlugin) LoadConfig(func(interface{}) error) {}
+func (p *testPlugin) Icon() *image.Image                 { return nil }
+func (p *testPlugin) Name() string                       { return p.name }
+
+func (p *testPlugin) Catalog(ctx context.Context, callback api.CatalogCallback) error {
+	callback(p.items)
+	return nil
+}
+
+func (p *testPlugin) Suggest(ctx context.Context, input string, chain []api.Item, callback api.SuggestionCallback) {
+	if p.suggest == nil {
+		return
+	}
+
+	if items := p.suggest(input, chain); items != nil {
+		callback(items, api.MatchFuzzy)
+	}
+}
+
+func (p *testPlugin) Execute(ctx context.Context, item api.Item, action string) error {
+	p.executed <- execution{item, action}
+
+	if p.execute != nil {
+		return p.execute(item)
+	}
+	return nil
+}
+
+func newTestPlugin() *testPlugin {
+	return &testPlugin{
+		name: "test",
+		items: []api.Item{
+			{Label: "Repository", Category: api.User, ArgsHint: api.Accepted},
+			{Label: "Encode", Category: api.User, ArgsHint: api.Required},
+			{Label: "Other", Category: api.User},
+		},
+		suggest: func(input string, chain []api.Item) []api.Item {
+			if len(chain) == 0 {
+				return nil
+			}
+
+			return []api.Item{
+				{Label: "Pull requests", Category: api.User},
+				{Label: "Tags", Category: api.User},
+			}
+		},
+		executed: make(chan execution, 1),
+	}
+}
+
+func newTestEngine(plugins ...api.Plugin) *Engine {
+	e := New(hclog.NewNullLogger(), plugins)
+	e.Catalog()
+	return e
+}
+
+func labels(suggestions []SuggestItem) []string {
+	var result []string
+	for _, each := range suggestions {
+		result = append(result, each.Item.DisplayName())
+	}
+	return result
+}
+
+func waitForLabels(t *testing.T, e *Engine, expected ...string) {
+	t.Helper()
+
+	assert.Eventually(t, func() bool {
+		return assert.ObjectsAreEqual(expected, labels(e.Suggestions()))
+	}, time.Second, time.Millisecond, "expected suggestions %v, got %v", expected, labels(e.Suggestions()))
+}
+
+func TestEngine_SearchCatalog(t *testing.T) {
+	e := newTestEngine(newTestPlugin())
+
+	e.Search("repo")
+	waitForLabels(t, e, "Repository")
+	assert.Equal(t, 0, e.SelectedIndex())
+
+	e.Search("")
+	assert.Empty(t, e.Suggestions())
+	assert.Equal(t, -1, e.SelectedIndex())
+}
+
+func TestEngine_Select(t *testing.T) {
+	e := newTestEngine(newTestPlugin())
+
+	e.Search("e")
+	waitForLabels(t, e, "Encode", "Other", "Repository")
+
+	assert.True(t, e.SelectNext())
+	assert.True(t, e.SelectNext())
+	assert.False(t, e.SelectNext())
+	assert.Equal(t, 2, e.SelectedIndex())
+
+	assert.True(t, e.SelectPrevious())
+	item, ok := e.CurrentItem()
+	assert.True(t, ok)
+	assert.Equal(t, "Other", item.DisplayName())
+
+	assert.False(t, e.Select(3))
+	assert.Equal(t, 1, e.SelectedIndex())
+}
+
+func TestEngine_PushAndPop(t *testing.T) {
+	e := newTestEngine(newTestPlugin())
+
+	e.Search("repo")
+	waitForLabels(t, e, "Repository")
+
+	assert.True(t, e.Push())
+	assert.Len(t, e.Stack(), 1)
+	assert.Equal(t, "Repository", e.Stack()[0].Item().DisplayName())
+	assert.Equal(t, "", e.Query())
+	waitForLabels(t, e, "Pull requests", "Tags")
+
+	e.Search("tag")
+	waitForLabels(t, e, "Tags")
+
+	// The first cancel clears the query, the second one pops the stack
+	e.Cancel()
+	assert.Equal(t, "", e.Query())
+	assert.Len(t, e.Stack(), 1)
+
+	e.Cancel()
+	assert.Empty(t, e.Stack())
+	assert.Equal(t, "repo", e.Query())
+	waitForLabels(t, e, "Repository")
+}
+
+func TestEngine_PushForbidden(t *testing.T) {
+	e := newTestEngine(newTestPlugin())
+
+	e.Search("other")
+	waitForLabels(t, e, "Other")
+
+	assert.False(t, e.Push())
+	assert.Empty(t, e.Stack())
+}
+
+func TestEngine_ExecuteRequiresArgument(t *testing.T) {
+	e := newTestEngine(newTestPlugin())
+
+	e.Search("enc")
+	waitForLabels(t, e, "Encode")
+
+	e.Execute()
+	assert.Len(t, e.Stack(), 1)
+	assert.Empty(t, e.Suggestions())
+}
+
+func TestEngine_Execute(t *testing.T) {
+	p := newTestPlugin()
+	e := newTestEngine(p)
+
+	dismissed := make(chan bool, 1)
+	e.Subscribe(func(evt Event) {
+		if evt == EventDismissed {
+			dismissed <- true
+		}
+	})
+
+	e.Search("other")
+	waitForLabels(t, e, "Other")
+	e.Execute()
+
+	select {
+	case ex := <-p.executed:
+		assert.Equal(t, "Other", ex.item.Label)
+		assert.Equal(t, api.DefaultAction, ex.action)
+	case <-time.After(time.Second):
+		t.Fatal("item was not executed")
+	}
+
+	assert.True(t, <-dismissed)
+}
+
+func TestEngine_CancelAtRootDismisses(t *testing.T) {
+	e := newTestEngine(newTestPlugin())
+
+	dismissed := false
+	e.Subscribe(func(evt Event) {
+		if evt == EventDismissed {
+			dismissed = true
+		}
+	})
+
+	e.Cancel()
+	assert.True(t, dismissed)
+}
+
+func TestEngine_HistoryRanking(t *testing.T) {
+	p := newTestPlugin()
+	p.items = []api.Item{
+		{Label: "go-anywhere", Category: api.User},
+		{Label: "go", Category: api.User},
+	}
+	e := newTestEngine(p)
+
+	e.Search("go")
+	waitForLabels(t, e, "go", "go-anywhere")
+
+	// Once the query resolved to the less relevant item it moves to the top
+	e.Select(1)
+	e.Execute()
+	<-p.executed
+
+	e.Search("go")
+	waitForLabels(t, e, "go-anywhere", "go")
+
+	e.Search("g")
+	waitForLabels(t, e, "go-anywhere", "go")
+	assert.Len(t, e.History().Entries(), 1)
+}
+
+func TestEngine_MergeSuggestions(t *testing.T) {
+	p := newTestPlugin()
+	e := newTestEngine(p)
+
+	suggest := func(score float64, items ...api.Item) []SuggestItem {
+		var result []SuggestItem
+		for _, each := range items {
+			result = append(result, SuggestItem{Item: asInternalItem(each, p), Score: score})
+		}
+		return result
+	}
+
+	a := api.Item{ID: "a", Label: "Alpha"}
+	b := api.Item{ID: "b", Label: "Beta"}
+	c := api.Item{ID: "c", Label: "Gamma"}
+
+	e.addSuggestions(e.generation, suggest(0.5, a, b))
+	assert.True(t, e.Select(1))
+
+	// The same items are suggested again, they are merged and the selection stays on Beta
+	e.addSuggestions(e.generation, suggest(0.8, c, b, a))
+	assert.Equal(t, []string{"Gamma", "Beta", "Alpha"}, labels(e.Suggestions()))
+	assert.Equal(t, 0.8, e.Suggestions()[1].Score)
+
+	item, _ := e.CurrentItem()
+	assert.Equal(t, "Beta", item.DisplayName())
+
+	// When the selected item disappears the first item is selected
+	e.setSuggestions(e.generation, suggest(0.5, a, c))
+	assert.Equal(t, 0, e.SelectedIndex())
+}
+
+// echoPlugin suggests the input followed by the name of the plugin
+func echoPlugin(name string) *testPlugin {
+	p := newTestPlugin()
+	p.name = name
+	p.items = nil
+	p.suggest = func(input string, chain []api.Item) []api.Item {
+		return []api.Item{{Label: input + " " + name, Category: api.User}}
+	}
+	return p
+}
+
+// blockingPlugin holds back the suggestions for the blocked inputs until the test releases them. Cancelling the
+// search does not interrupt it, like a plugin that does not honour the context.
+type blockingPlugin struct {
+	testPlugin
+	mutex   sync.Mutex
+	blocked map[string]chan struct{}
+	asked   []string
+
+	// returned receives every input once the plugin delivered its suggestions
+	returned chan string
+}
+
+func blocking(p *testPlugin, blocked ...string) *blockingPlugin {
+	b := &blockingPlugin{testPlugin: *p, blocked: make(map[string]chan struct{}), returned: make(chan string, 100)}
+	for _, input := range blocked {
+		b.blocked[input] = make(chan struct{})
+	}
+	return b
+}
+
+func (p *blockingPlugin) Suggest(ctx context.Context, input string, chain []api.Item, callback api.SuggestionCallback) {
+	p.mutex.Lock()
+	p.asked = append(p.asked, input)
+	gate := p.blocked[input]
+	p.mutex.Unlock()
+
+	if gate != nil {
+		<-gate
+	}
+	p.testPlugin.Suggest(ctx, input, chain, callback)
+	p.returned <- input
+}
+
+func (p *blockingPlugin) release(input string) {
+	close(p.blocked[input])
+}
+
+func (p *blockingPlugin) askedFor() []string {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return append([]string(nil), p.asked...)
+}
+
+// waitReturned waits until the plugin delivered its suggestions for the input
+func (p *blockingPlugin) waitReturned(t *testing.T, input string) {
+	t.Helper()
+
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case each := <-p.returned:
+			if each == input {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("%s did not return the suggestions for %q", p.name, input)
+		}
+	}
+}
+
+func TestEngine_SearchPrefix(t *testing.T) {
+	other := blocking(echoPlugin("other"))
+	e := newTestEngine(newTestPlugin(), other)
+	e.SetPrefixes(map[string]string{"t": "test", "Enc": "test:Encode", "x": "unknown"})
+
+	// A plugin keyword limits the search to the catalog and the suggestions of the plugin
+	e.Search("t rep")
+	waitForLabels(t, e, "Repository")
+	assert.Equal(t, "rep", e.Query())
+
+	stack := e.Stack()
+	assert.Len(t, stack, 1)
+	assert.True(t, stack[0].IsScope())
+
+	// Items pushed in a scope are passed to the plugin without the scope
+	assert.True(t, e.Push())
+	waitForLabels(t, e, "Pull requests", "Tags")
+
+	// An item keyword pushes the item, popping it restores the keyword
+	e.Reset()
+	e.Search("enc tag")
+	waitForLabels(t, e, "Tags")
+	assert.Equal(t, "tag", e.Query())
+	assert.Equal(t, "Encode", e.Stack()[0].Item().DisplayName())
+	assert.False(t, e.Stack()[0].IsScope())
+
+	assert.True(t, e.Pop())
+	assert.Equal(t, "enc", e.Query())
+
+	// Keywords must be followed by a space and only apply at the root
+	e.Reset()
+	e.Search("enc")
+	waitForLabels(t, e, "Encode", "enc other")
+	assert.Empty(t, e.Stack())
+
+	// Only the searches at the root reached the other plugin
+	assert.Equal(t, []string{"enc"}, other.askedFor())
+
+	e.Search("x y")
+	assert.Empty(t, e.Stack())
+}
+
+func TestEngine_RoutePrefixCase(t *testing.T) {
+	e := newTestEngine(newTestPlugin())
+	e.SetPrefixes(map[string]string{"ka": "test"})
+
+	rest, ok := e.routePrefix("KA Rep")
+	assert.True(t, ok)
+	assert.Equal(t, "Rep", rest)
+	assert.Equal(t, "KA", e.Stack()[0].searchText)
+
+	// The Kelvin sign lowercases to "k" but is longer in bytes, so the input is not cut within it
+	e.Reset()
+	_, ok = e.routePrefix("\u212aa rep")
+	assert.False(t, ok)
+	assert.Empty(t, e.Stack())
+}
+
+func TestEngine_Open(t *testing.T) {
+	e := newTestEngine(newTestPlugin(), echoPlugin("other"))
+	e.SetPrefixes(map[string]string{"enc": "test:Encode"})
+
+	// A plugin limits the search like a keyword, popping the scope returns to the root
+	e.Search("something")
+	assert.NoError(t, e.Open("test", "rep"))
+	waitForLabels(t, e, "Repository")
+	assert.Equal(t, "rep", e.Query())
+	assert.Len(t, e.Stack(), 1)
+	assert.True(t, e.Stack()[0].IsScope())
+
+	assert.True(t, e.Pop())
+	assert.Equal(t, "", e.Query())
+
+	// The query is searched as if it was typed
+	assert.NoError(t, e.Open("", "enc tag"))
+	waitForLabels(t, e, "Tags")
+	assert.Equal(t, "Encode", e.Stack()[0].Item().DisplayName())
+
+	assert.EqualError(t, e.Open("unknown", ""), `unknown plugin "unknown"`)
+	assert.Len(t, e.Stack(), 1, "an unknown plugin leaves the search as it is")
+}
+
+func TestEngine_ExecuteIdentity(t *testing.T) {
+	p := newTestPlugin()
+	e := newTestEngine(p)
+
+	pushed, err := e.ExecuteIdentity("test:Other")
+	assert.NoError(t, err)
+	assert.False(t, pushed)
+
+	select {
+	case ex := <-p.executed:
+		assert.Equal(t, "Other", ex.item.Label)
+		assert.Equal(t, api.DefaultAction, ex.action)
+	case <-time.After(time.Second):
+		t.Fatal("item was not executed")
+	}
+
+	// An item that requires an argument waits for it
+	pushed, err = e.ExecuteIdentity("test:Encode")
+	assert.NoError(t, err)
+	assert.True(t, pushed)
+	assert.Equal(t, "Encode", e.Stack()[0].Item().DisplayName())
+
+	_, err = e.ExecuteIdentity("test:Missing")
+	assert.EqualError(t, err, `unknown item "test:Missing"`)
+}
+
+func TestEngine_ExecuteIdentityFailure(t *testing.T) {
+	p := newTestPlugin()
+	p.execute = func(item api.Item) error {
+		return errors.New("offline")
+	}
+
+	e := newTestEngine(p)
+	failed := make(chan struct{}, 1)
+	e.Subscribe(func(evt Event) {
+		if evt == EventFailed {
+			failed <- struct{}{}
+		}
+	})
+
+	// The frontend is hidden while the item executes, so it is told to show the failure
+	_, err := e.ExecuteIdentity("test:Other")
+	assert.NoError(t, err)
+
+	select {
+	case <-failed:
+	case <-time.After(time.Second):
+		t.Fatal("failure was not reported")
+	}
+	waitForLabels(t, e, "test: offline")
+}
+
+func TestEngine_OpenItem(t *testing.T) {
+	p := newTestPlugin()
+	p.suggest = func(input string, chain []api.Item) []api.Item {
+		switch len(chain) {
+		case 1:
+			return []api.Item{
+				{ID: "pulls", Label: "Pull requests", Category: api.User, ArgsHint: api.Accepted},
+				{ID: "tags", Label: "Tags", Category: api.User},
+			}
+		case 2:
+			return []api.Item{{Label: "#1 Fix the build", Category: api.User}}
+		}
+		return nil
+	}
+
+	e := newTestEngine(p)
+
+	// A suggestion that can be pushed is opened as if it was selected and pushed
+	assert.NoError(t, e.OpenItem("test:Repository", "pulls"))
+	waitForLabels(t, e, "#1 Fix the build")
+	assert.Len(t, e.Stack(), 2)
+	assert.Equal(t, "Pull requests", e.Stack()[1].Item().DisplayName())
+
+	// Other suggestions are executed
+	assert.NoError(t, e.OpenItem("test:Repository", "tags"))
+
+	select {
+	case ex := <-p.executed:
+		assert.Equal(t, "Tags", ex.item.Label)
+	case <-time.After(time.Second):
+		t.Fatal("sub-item was not executed")
+	}
+
+	assert.EqualError(t, e.OpenItem("test:Missing", "pulls"), `unknown item "test:Missing"`)
+	assert.Error(t, e.OpenItem("test:Other", "pulls"), "an item without suggestions has no sub-items")
+}
+
+func TestEngine_ShowFailure(t *testing.T) {
+	e := newTestEngine(newTestPlugin())
+	e.Search("rep")
+	waitForLabels(t, e, "Repository")
+
+	e.ShowFailure("Ctrl+Alt+G", errors.New(`unknown item "github:our-org/main-repo"`))
+	waitForLabels(t, e, `Ctrl+Alt+G: unknown item "github:our-org/main-repo"`)
+	assert.Empty(t, e.Query())
+}
+
+func TestEngine_SearchTexts(t *testing.T) {
+	p := newTestPlugin()
+	p.items = []api.Item{
+		{ID: "proxy", Label: "go-proxy", Description: "A caching proxy for Go modules", Category: api.User},
+		{ID: "code", Label: "Visual Studio Code", Keywords: []string{"vscode"}, Category: api.User},
+		{ID: "outlook", Label: "Outlook", Category: api.User},
+	}
+
+	e := newTestEngine(p)
+	e.SetAliases(map[string]string{"mail": "test:outlook", " ": "test:code"})
+
+	// Words of the description and keywords find the item with a lower score than its label
+	e.Search("caching")
+	waitForLabels(t, e, "go-proxy")
+
+	e.Search("vscode")
+	waitForLabels(t, e, "Visual Studio Code")
+	assert.Less(t, e.Suggestions()[0].Score, 1.0)
+	assert.Empty(t, e.Suggestions()[0].Ranges)
+
+	// An alias finds its item as well as its label does
+	e.Search("mail")
+	waitForLabels(t, e, "Outlook")
+	assert.Equal(t, 1.0, e.Suggestions()[0].Score)
+
+	e.SetAliases(nil)
+	e.Search("mail")
+	waitForLabels(t, e)
+}
+
+func TestEngine_DropsSupersededResults(t *testing.T) {
+	p := blocking(echoPlugin("github"), "a")
+	e := newTestEngine(p)
+
+	e.Search("a")
+	e.Search("ab")
+	waitForLabels(t, e, "ab github")
+
+	// The response for the first query arrives after the response for the second one
+	p.release("a")
+	p.waitReturned(t, "a")
+	assert.Equal(t, []string{"ab github"}, labels(e.Suggestions()))
+}
+
+func TestEngine_OutOfOrderPlugins(t *testing.T) {
+	slow := blocking(echoPlugin("slow"), "a", "ab")
+	fast := blocking(echoPlugin("fast"), "a")
+	e := newTestEngine(slow, fast)
+
+	e.Search("a")
+	fast.release("a")
+	waitForLabels(t, e, "a fast")
+
+	e.Search("ab")
+	waitForLabels(t, e, "ab fast")
+	slow.release("ab")
+	assert.Eventually(t, func() bool { return len(e.Suggestions()) == 2 }, time.Second, time.Millisecond)
+
+	// The slow response for the first query arrives last
+	slow.release("a")
+	slow.waitReturned(t, "a")
+	assert.ElementsMatch(t, []string{"ab fast", "ab slow"}, labels(e.Suggestions()))
+}
+
+func TestEngine_DropsResultsAfterPop(t *testing.T) {
+	p := blocking(newTestPlugin(), "")
+	e := newTestEngine(p)
+
+	e.Search("repo")
+	waitForLabels(t, e, "Repository")
+	e.Push()
+
+	// Leave the item before its plugin responded
+	e.Cancel()
+	assert.Empty(t, e.Stack())
+	waitForLabels(t, e, "Repository")
+
+	p.release("")
+	p.waitReturned(t, "")
+	assert.Equal(t, []string{"Repository"}, labels(e.Suggestions()))
+}
+
+func TestEngine_SuggestTimeout(t *testing.T) {
+	p := blocking(echoPlugin("github"), "a")
+	defer p.release("a")
+	e := newTestEngine(p)
+	e.SetPluginOptions("github", PluginOptions{SuggestTimeout: 20 * time.Millisecond})
+
+	e.Search("a")
+	waitForLabels(t, e, "github: timed out")
+
+	item, _ := e.CurrentItem()
+	assert.Equal(t, api.Error, item.Item.Category)
+}
+
+func TestEngine_SuggestReturnsWithPlugin(t *testing.T) {
+	contexts := make(chan context.Context, 1)
+	p := &suggestPlugin{testPlugin: *newTestPlugin(), suggest: func(ctx context.Context) {
+		contexts <- ctx
+	}}
+	e := newTestEngine(p)
+
+	// The context ends as soon as the plugin returned, not at its deadline
+	e.Search("a")
+	ctx := <-contexts
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("the context of the plugin is still valid after it returned")
+	}
+}
+
+func TestEngine_SuggestPanic(t *testing.T) {
+	p := newTestPlugin()
+	p.suggest = func(input string, chain []api.Item) []api.Item {
+		panic("boom")
+	}
+	e := newTestEngine(p)
+
+	e.Search("repo")
+	waitForLabels(t, e, "Repository", "test: plugin panicked: boom")
+
+	// Other plugins keep working
+	e.Search("other")
+	waitForLabels(t, e, "Other", "test: plugin panicked: boom")
+}
+
+type catalogPlugin struct {
+	testPlugin
+	catalog func(ctx context.Context, callback api.CatalogCallback) error
+}
+
+func (p *catalogPlugin) Catalog(ctx context.Context, callback api.CatalogCallback) error {
+	return p.catalog(ctx, callback)
+}
+
+type suggestPlugin struct {
+	testPlugin
+	suggest func(ctx context.Context)
+}
+
+func (p *suggestPlugin) Suggest(ctx context.Context, input string, chain []api.Item, callback api.SuggestionCallback) {
+	p.suggest(ctx)
+}
+
+func TestEngine_CatalogNotOverlapping(t *testing.T) {
+	release := make(chan struct{})
+	calls := make(chan struct{}, 10)
+	p := &catalogPlugin{testPlugin: *newTestPlugin(), catalog: func(ctx context.Context, callback api.CatalogCallback) error {
+		calls <- struct{}{}
+		<-release
+		return nil
+	}}
+
+	e := New(hclog.NewNullLogger(), []api.Plugin{p})
+	e.SetPluginOptions("test", PluginOptions{CatalogTimeout: 20 * time.Millisecond})
+
+	assert.EqualError(t, e.RefreshCatalog(p), "timed out")
+
+	// The plugin is not asked again while it is still building the catalog that timed out
+	assert.Equal(t, errCatalogRunning, e.RefreshCatalog(p))
+	assert.Len(t, calls, 1)
+
+	close(release)
+	assert.Eventually(t, func() bool {
+		return e.RefreshCatalog(p) == nil
+	}, time.Second, time.Millisecond)
+	assert.Len(t, calls, 2)
+}
+
+type configuredPlugin struct {
+	catalogPlugin
+	loaded chan struct{}
+}
+
+func (p *configuredPlugin) LoadConfig(func(interface{}) error) {
+	p.loaded <- struct{}{}
+}
+
+func TestEngine_ConfigureCancelsCatalog(t *testing.T) {
+	started := make(chan struct{}, 1)
+	var calls int32
+	p := &configuredPlugin{loaded: make(chan struct{}, 1)}
+	p.testPlugin = *newTestPlugin()
+	p.catalog = func(ctx context.Context, callback api.CatalogCallback) error {
+		if atomic.AddInt32(&calls, 1) > 1 {
+			callback(p.items)
+			return nil
+		}
+
+		// The catalog of the previous configuration returns without an error once it is cancelled
+		callback([]api.Item{{Label: "Stale", Category: api.User}})
+		started <- struct{}{}
+		<-ctx.Done()
+		return nil
+	}
+
+	e := New(hclog.NewNullLogger(), []api.Plugin{p})
+
+	refreshed := make(chan error, 1)
+	go func() { refreshed <- e.RefreshCatalog(p) }()
+	<-started
+
+	// The configuration is loaded right away, the running catalog is cancelled and dropped
+	e.ConfigurePlugin(p, nil)
+	assert.Len(t, p.loaded, 1)
+	assert.Equal(t, errCatalogCancelled, <-refreshed)
+
+	assert.NoError(t, e.RefreshCatalog(p))
+	e.Search("stale")
+	waitForLabels(t, e)
+	e.Search("repo")
+	waitForLabels(t, e, "Repository")
+}
+
+func TestEngine_CatalogFailures(t *testing.T) {
+	blocking := &catalogPlugin{testPlugin: *newTestPlugin(), catalog: func(ctx context.Context, callback api.CatalogCallback) error {
+		// The items found before the timeout are kept
+		callback([]api.Item{{Label: "Other", Category: api.User}})
+		select {}
+	}}
+	blocking.name = "blocking"
+
+	panicking := &catalogPlugin{testPlugin: *newTestPlugin(), catalog: func(ctx context.Context, callback api.CatalogCallback) error {
+		panic("boom")
+	}}
+	panicking.name = "panicking"
+	panicking.items = nil
+
+	e := New(hclog.NewNullLogger(), []api.Plugin{blocking, panicking})
+	e.SetPluginOptions("blocking", PluginOptions{CatalogTimeout: 20 * time.Millisecond})
+	e.Catalog()
+
+	e.Search("other")
+	waitForLabels(t, e, "Other", "blocking: timed out", "panicking: plugin panicked: boom")
+}
+
+func TestEngine_ExecuteFailure(t *testing.T) {
+	p := newTestPlugin()
+	p.execute = func(item api.Item) error {
+		return errors.New("clipboard unavailable")
+	}
+	e := newTestEngine(p)
+
+	dismissed := false
+	e.Subscribe(func(evt Event) {
+		if evt == EventDismissed {
+			dismissed = true
+		}
+	})
+
+	e.Search("other")
+	waitForLabels(t, e, "Other")
+	e.Execute()
+	<-p.executed
+
+	waitForLabels(t, e, "test: clipboard unavailable", "Other")
+	assert.False(t, dismissed)
+}
+
+func TestEngine_ExecutePanic(t *testing.T) {
+	p := newTestPlugin()
+	p.execute = func(item api.Item) error {
+		panic("boom")
+	}
+	e := newTestEngine(p)
+
+	e.Search("other")
+	waitForLabels(t, e, "Other")
+	e.Execute()
+	<-p.executed
+
+	waitForLabels(t, e, "test: plugin panicked: boom", "Other")
+}
+
+func TestEngine_Actions(t *testing.T) {
+	p := newTestPlugin()
+	p.items = []api.Item{{
+		Label:    "Pull request",
+		Category: api.Url,
+		Target:   "https://github.com/arjenjb/go-anywhere/pull/1",
+		Actions:  []api.Action{{ID: "copy-branch", Label: "Copy branch name"}},
+	}}
+	e := newTestEngine(p)
+
+	e.Search("pull")
+	waitForLabels(t, e, "Pull request")
+
+	item, _ := e.CurrentItem()
+	assert.Equal(t, []api.Action{
+		{ID: api.DefaultAction, Label: "Open in browser"},
+		{ID: ActionCopyTarget, Label: "Copy URL"},
+		{ID: "copy-branch", Label: "Copy branch name"},
+	}, Actions(item))
+
+	assert.False(t, e.ExecuteAction(3))
+	assert.True(t, e.ExecuteAction(2))
+
+	ex := <-p.executed
+	assert.Equal(t, "Pull request", ex.item.Label)
+	assert.Equal(t, "copy-branch", ex.action)
+}
+
+func TestEngine_ActionMenu(t *testing.T) {
+	p := newTestPlugin()
+	p.items = []api.Item{{
+		Label:    "= 255",
+		Category: api.User,
+		Actions: []api.Action{
+			{ID: "copy-decimal", Label: "Copy decimal"},
+			{ID: "copy-hex", Label: "Copy hex"},
+		},
+	}}
+	e := newTestEngine(p)
+
+	e.Search("255")
+	waitForLabels(t, e, "= 255")
+
+	assert.True(t, e.ShowActions())
+	assert.True(t, e.Stack()[0].IsActionMenu())
+	waitForLabels(t, e, "Execute", "Copy decimal", "Copy hex")
+
+	e.Search("hex")
+	waitForLabels(t, e, "Copy hex")
+
+	// Leaving the menu restores the search
+	e.Cancel()
+	e.Cancel()
+	assert.Empty(t, e.Stack())
+	assert.Equal(t, "255", e.Query())
+
+	e.ShowActions()
+	e.Search("hex")
+	waitForLabels(t, e, "Copy hex")
+	e.Execute()
+
+	ex := <-p.executed
+	assert.Equal(t, "= 255", ex.item.Label)
+	assert.Equal(t, "copy-hex", ex.action)
+	assert.Equal(t, "255", e.History().Entries()[0].Query)
+}
+
+type matchPlugin struct {
+	testPlugin
+	match api.Match
+}
+
+func (p *matchPlugin) Suggest(ctx context.Context, input string, chain []api.Item, callback api.SuggestionCallback) {
+	callback(p.suggest(input, chain), p.match)
+}
+
+func TestEngine_MatchModes(t *testing.T) {
+	p := &matchPlugin{testPlugin: *newTestPlugin()}
+	p.items = nil
+	p.suggest = func(input string, chain []api.Item) []api.Item {
+		return []api.Item{
+			{Label: "Fix the pull request list", Category: api.User},
+			{Label: "Pull request list", Category: api.User},
+			{Label: "Add pull", Category: api.User, Score: 0.9},
+		}
+	}
+	e := newTestEngine(p)
+
+	p.match = api.MatchOrdered
+	e.Search("pull")
+	waitForLabels(t, e, "Fix the pull request list", "Pull request list", "Add pull")
+
+	p.match = api.MatchPrefix
+	e.Search("pull")
+	waitForLabels(t, e, "Pull request list")
+
+	p.match = api.MatchWord
+	e.Search("list pull")
+	waitForLabels(t, e, "Pull request list", "Fix the pull request list")
+
+	// The score of the plugin replaces the score of matching
+	p.match = api.MatchFuzzy
+	e.Search("pull")
+	waitForLabels(t, e, "Add pull", "Pull request list", "Fix the pull request list")
+}
+
+func TestEngine_PluginRanking(t *testing.T) {
+	calculator := &matchPlugin{testPlugin: *newTestPlugin(), match: api.MatchAny}
+	calculator.name = "expr"
+	calculator.items = nil
+	calculator.suggest = func(input string, chain []api.Item) []api.Item {
+		return []api.Item{{Label: "= 4", Category: api.User, HitHint: api.HitTop}}
+	}
+
+	catalog := newTestPlugin()
+	catalog.items = []api.Item{{Label: "2+2", Category: api.User}}
+	catalog.suggest = nil
+
+	e := newTestEngine(calculator, catalog)
+
+	// The exact catalog match ranks below the result of the calculation
+	e.Search("2+2")
+	waitForLabels(t, e, "= 4", "2+2")
+}
+
+func TestEngine_PluginPriority(t *testing.T) {
+	low := newTestPlugin()
+	low.name = "low"
+	low.items = []api.Item{{Label: "Repo", Category: api.User}}
+
+	high := newTestPlugin()
+	high.name = "high"
+	high.items = []api.Item{{Label: "Repositories", Category: api.User}}
+
+	e := newTestEngine(low, high)
+
+	e.Search("repo")
+	waitForLabels(t, e, "Repo", "Repositories")
+
+	// The plugin with the higher priority ranks above the better match
+	e.SetPluginOptions("high", PluginOptions{Priority: 1})
+	e.Search("repos")
+	e.Search("repo")
+	waitForLabels(t, e, "Repositories", "Repo")
+}
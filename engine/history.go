@@ -0,0 +1,319 @@
+package engine
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// HistoryEntry records a single execution of an item
+type HistoryEntry struct {
+	Item  string    `json:"item"`  // Identity of the executed item
+	Query string    `json:"query"` // The query that was typed when the item was executed
+	Time  time.Time `json:"time"`
+}
+
+// History is the usage history of the launcher. It is used to rank items that are executed often and recently
+// higher than others, and to learn which item a query usually resolves to.
+type History struct {
+	mutex   sync.Mutex
+	path    string
+	entries []HistoryEntry
+	now     func() time.Time
+
+	// The scores computed from the entries, they are dropped when an entry is recorded. The frecency of the items
+	// also expires, since the executions age.
+	frecency     map[string]float64
+	frecencyTime time.Time
+	generation   uint64
+	affinity     map[string]map[string]float64 // The affinity of the items by query
+
+	// Executions are written in batches, the next write is pending while dirty is set
+	dirty     bool
+	saveErr   error // The error of the last write in the background
+	saveMutex sync.Mutex
+}
+
+// HistoryScores are the scores of the history for a single query, they are taken once per search so ranking the
+// matches does not go over the history for every item
+type HistoryScores struct {
+	frecency   map[string]float64
+	affinity   map[string]float64
+	generation uint64 // Changes whenever the frecency is computed again, so it can be cached
+}
+
+// frecencyGenerations numbers the computed frecencies of all histories
+var frecencyGenerations uint64
+
+// Age buckets used to weigh an execution, recent executions count more than old ones
+var frecencyBuckets = []struct {
+	age    time.Duration
+	weight float64
+}{
+	{4 * 24 * time.Hour, 100},
+	{14 * 24 * time.Hour, 70},
+	{31 * 24 * time.Hour, 50},
+	{90 * 24 * time.Hour, 30},
+}
+
+const frecencyOldWeight = 10
+
+// frecencyHalfScore is the frecency at which an item receives half of the maximum frecency bonus
+const frecencyHalfScore = 200
+
+// frecencyWeight is the maximum bonus an item can receive for being executed often and recently
+const frecencyWeight = 0.5
+
+// frecencyExpiry is how long the computed frecency is used before the age of the executions is taken into account
+// again
+const frecencyExpiry = time.Hour
+
+// historySaveDelay is how long executions are collected before the history is written
+const historySaveDelay = 2 * time.Second
+
+// affinityCacheSize is the number of queries whose affinities are kept, typing a query only needs the last few
+const affinityCacheSize = 64
+
+// NewHistory returns an empty history that is only kept in memory
+func NewHistory() *History {
+	return &History{now: time.Now}
+}
+
+// OpenHistory loads the history from the given file, the file does not have to exist yet. Recorded executions are
+// written back to it in the background, Flush writes them right away.
+func OpenHistory(path string) (*History, error) {
+	h := NewHistory()
+	h.path = path
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return h, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &h.entries); err != nil {
+		return nil, err
+	}
+
+	return h, nil
+}
+
+// Record adds an execution of the item with the given identity. The history is written within historySaveDelay, the
+// error is that of the previous write if it failed.
+func (h *History) Record(item string, query string) error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.entries = append(h.entries, HistoryEntry{
+		Item:  item,
+		Query: strings.ToLower(strings.TrimSpace(query)),
+		Time:  h.now(),
+	})
+	h.invalidate()
+
+	if h.path != "" && !h.dirty {
+		h.dirty = true
+		time.AfterFunc(historySaveDelay, func() {
+			_ = h.Flush()
+		})
+	}
+
+	err := h.saveErr
+	h.saveErr = nil
+
+	return err
+}
+
+// Flush writes the executions that were recorded since the last write
+func (h *History) Flush() error {
+	h.mutex.Lock()
+	dirty := h.dirty
+	h.mutex.Unlock()
+
+	if !dirty {
+		return nil
+	}
+
+	err := h.save()
+
+	h.mutex.Lock()
+	h.saveErr = err
+	h.mutex.Unlock()
+
+	return err
+}
+
+// Prune removes all entries older than maxAge and keeps at most maxEntries of the most recent entries
+func (h *History) Prune(maxAge time.Duration, maxEntries int) error {
+	h.mutex.Lock()
+
+	sort.SliceStable(h.entries, func(i, j int) bool {
+		return h.entries[i].Time.Before(h.entries[j].Time)
+	})
+
+	threshold := h.now().Add(-maxAge)
+	first := sort.Search(len(h.entries), func(i int) bool {
+		return h.entries[i].Time.After(threshold)
+	})
+
+	if len(h.entries)-first > maxEntries {
+		first = len(h.entries) - maxEntries
+	}
+
+	h.entries = append([]HistoryEntry(nil), h.entries[first:]...)
+	h.invalidate()
+	h.mutex.Unlock()
+
+	return h.save()
+}
+
+// Entries returns a copy of all recorded entries
+func (h *History) Entries() []HistoryEntry {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	return append([]HistoryEntry(nil), h.entries...)
+}
+
+// Frecency returns the combined frequency and recency score of an item, recent executions weigh more
+func (h *History) Frecency(item string) float64 {
+	return h.Scores("").frecency[item]
+}
+
+// Affinity returns the fraction of executions, for which the typed query started with the given query, that resolved
+// to the item. It is 1.0 when the query always led to this item.
+func (h *History) Affinity(query string, item string) float64 {
+	return h.Scores(query).affinity[item]
+}
+
+// Bonus returns the value that is added to the match score of an item, see HistoryScores.Bonus
+func (h *History) Bonus(query string, item string) float64 {
+	return h.Scores(query).Bonus(item)
+}
+
+// Scores returns the frecency of every item and the affinity of every item for the query
+func (h *History) Scores(query string) HistoryScores {
+	query = strings.ToLower(strings.TrimSpace(query))
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	now := h.now()
+	if h.frecency == nil || now.Sub(h.frecencyTime) > frecencyExpiry {
+		h.frecency = h.computeFrecency(now)
+		h.frecencyTime = now
+		h.generation = atomic.AddUint64(&frecencyGenerations, 1)
+	}
+
+	if len(query) == 0 {
+		return HistoryScores{frecency: h.frecency, generation: h.generation}
+	}
+
+	affinity, found := h.affinity[query]
+	if !found {
+		if h.affinity == nil || len(h.affinity) >= affinityCacheSize {
+			h.affinity = make(map[string]map[string]float64)
+		}
+
+		affinity = h.computeAffinity(query)
+		h.affinity[query] = affinity
+	}
+
+	return HistoryScores{frecency: h.frecency, affinity: affinity, generation: h.generation}
+}
+
+// Bonus returns the value that is added to the match score of an item, it ranges from 0.0 for items that were never
+// executed up to 1.5 for items that are executed very often and always for this query. Since match scores range from
+// 0.0 to 1.0 an item the query usually resolves to outranks better matching items.
+func (s HistoryScores) Bonus(item string) float64 {
+	f := s.frecency[item]
+
+	return frecencyWeight*f/(f+frecencyHalfScore) + s.affinity[item]
+}
+
+// empty returns true if no item gets a bonus
+func (s HistoryScores) empty() bool {
+	return len(s.frecency) == 0 && len(s.affinity) == 0
+}
+
+// invalidate drops the computed scores after the entries changed
+func (h *History) invalidate() {
+	h.frecency = nil
+	h.affinity = nil
+}
+
+func (h *History) computeFrecency(now time.Time) map[string]float64 {
+	result := make(map[string]float64)
+
+	for _, each := range h.entries {
+		weight := float64(frecencyOldWeight)
+		age := now.Sub(each.Time)
+
+		for _, b := range frecencyBuckets {
+			if age < b.age {
+				weight = b.weight
+				break
+			}
+		}
+
+		result[each.Item] += weight
+	}
+
+	return result
+}
+
+func (h *History) computeAffinity(query string) map[string]float64 {
+	total := 0
+	hits := make(map[string]int)
+
+	for _, each := range h.entries {
+		if strings.HasPrefix(each.Query, query) {
+			total++
+			hits[each.Item]++
+		}
+	}
+
+	result := make(map[string]float64, len(hits))
+	for item, count := range hits {
+		result[item] = float64(count) / float64(total)
+	}
+
+	return result
+}
+
+// save writes the entries, the writes are serialized so an older state cannot replace a newer one
+func (h *History) save() error {
+	h.saveMutex.Lock()
+	defer h.saveMutex.Unlock()
+
+	h.mutex.Lock()
+	h.dirty = false
+	data, err := json.Marshal(h.entries)
+	h.mutex.Unlock()
+
+	if h.path == "" {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(h.path), os.ModePerm); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so a crash cannot leave a truncated history behind
+	tmp := h.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, h.path)
+}
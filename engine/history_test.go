@@ -0,0 +1,82 @@
+package engine
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistory_Persistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.json")
+
+	h, err := OpenHistory(path)
+	assert.NoError(t, err)
+	assert.NoError(t, h.Record("github:repo", "Go "))
+	assert.NoFileExists(t, path, "executions are written in batches")
+	assert.NoError(t, h.Flush())
+
+	h, err = OpenHistory(path)
+	assert.NoError(t, err)
+
+	entries := h.Entries()
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "github:repo", entries[0].Item)
+	assert.Equal(t, "go", entries[0].Query)
+}
+
+func TestHistory_Frecency(t *testing.T) {
+	now := time.Now()
+	h := NewHistory()
+
+	h.now = func() time.Time { return now.Add(-100 * 24 * time.Hour) }
+	h.Record("old", "")
+	h.Record("old", "")
+
+	h.now = func() time.Time { return now }
+	h.Record("recent", "")
+
+	assert.Equal(t, 20.0, h.Frecency("old"))
+	assert.Equal(t, 100.0, h.Frecency("recent"))
+	assert.Equal(t, 0.0, h.Frecency("never"))
+	assert.Greater(t, h.Bonus("", "recent"), h.Bonus("", "old"))
+	assert.Equal(t, 0.0, h.Bonus("", "never"))
+}
+
+func TestHistory_Affinity(t *testing.T) {
+	h := NewHistory()
+	h.Record("github:go-anywhere", "go-any")
+	h.Record("github:go-anywhere", "go")
+	h.Record("github:gopls", "gop")
+
+	assert.Equal(t, 2.0/3.0, h.Affinity("go", "github:go-anywhere"))
+	assert.Equal(t, 1.0, h.Affinity("go-", "github:go-anywhere"))
+	assert.Equal(t, 0.0, h.Affinity("go-", "github:gopls"))
+	assert.Equal(t, 0.0, h.Affinity("", "github:gopls"))
+
+	// The scores are computed again after recording an execution
+	h.Record("github:gopls", "go")
+	assert.Equal(t, 2.0/4.0, h.Affinity("go", "github:go-anywhere"))
+	assert.Equal(t, 2.0/4.0, h.Affinity("go", "github:gopls"))
+}
+
+func TestHistory_Prune(t *testing.T) {
+	now := time.Now()
+	h := NewHistory()
+
+	for i := 10; i > 0; i-- {
+		at := now.Add(-time.Duration(i) * 24 * time.Hour)
+		h.now = func() time.Time { return at }
+		h.Record("item", "")
+	}
+	h.now = func() time.Time { return now }
+
+	assert.NoError(t, h.Prune(5*24*time.Hour+time.Minute, 100))
+	assert.Len(t, h.Entries(), 5)
+
+	assert.NoError(t, h.Prune(time.Hour*24*365, 2))
+	entries := h.Entries()
+	assert.Len(t, entries, 2)
+	assert.Equal(t, now.Add(-24*time.Hour), entries[1].Time)
+}
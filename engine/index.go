@@ -0,0 +1,541 @@
+package engine
+
+import (
+	"container/heap"
+	"math"
+	"math/bits"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"go-keyboard-launcher/api"
+)
+
+// catalogResultLimit is the maximum number of catalog items that are suggested for a search
+const catalogResultLimit = 200
+
+// catalogIndex is the searchable form of the root items. It keeps a posting list of the texts that contain each
+// rune, so a search only visits the texts that contain the runes of the search. A match does not need adjacent runes,
+// e.g. "lnchr" matches "launcher", which is why the lists are not of longer n-grams. For every text it also keeps a
+// signature of its runes and of the pairs of adjacent runes, and what it needs to tell the best score it can match
+// with, so texts that cannot make it into the results are skipped without scoring them.
+type catalogIndex struct {
+	items      []InternalItem
+	entries    []indexEntry       // The texts of all items, the texts of an item are next to each other
+	postings   map[rune][]int32   // The entries whose text contains the rune, in ascending order
+	identities map[string][]int32 // The items by identity, to find the items that are in the history
+
+	// The items with a frecency of the last history that was searched with, it only changes after an execution
+	historyMutex      sync.Mutex
+	historyGeneration uint64
+	historyItemsCache []int32
+}
+
+type indexEntry struct {
+	item      int // The index of the item in items
+	text      lookupText
+	signature uint64
+	bigrams   uint64
+	length    int // The number of runes of the text
+	initials  int // The number of words of the text, an acronym has to match their initials
+}
+
+func newCatalogIndex(items []InternalItem) *catalogIndex {
+	x := catalogIndex{
+		items:      items,
+		entries:    make([]indexEntry, 0, len(items)),
+		postings:   make(map[rune][]int32),
+		identities: make(map[string][]int32, len(items)),
+	}
+
+	for idx, each := range items {
+		identity := each.Identity()
+		x.identities[identity] = append(x.identities[identity], int32(idx))
+
+		for _, t := range each.lookupTexts() {
+			id := int32(len(x.entries))
+			label := []rune(t.text)
+
+			x.entries = append(x.entries, indexEntry{
+				item:      idx,
+				text:      t,
+				signature: signature(t.folded),
+				bigrams:   bigramSignature(t.folded),
+				length:    len(label),
+				initials:  initials(label),
+			})
+
+			for _, r := range t.folded {
+				if list := x.postings[r]; len(list) == 0 || list[len(list)-1] != id {
+					x.postings[r] = append(list, id)
+				}
+			}
+		}
+	}
+
+	return &x
+}
+
+// signature returns a bit set of the folded runes in s. Letters and digits have a bit of their own, all other runes
+// share the remaining bits.
+func signature(folded string) uint64 {
+	var result uint64
+
+	for _, r := range folded {
+		switch {
+		case r >= 'a' && r <= 'z':
+			result |= 1 << uint(r-'a')
+		case r >= '0' && r <= '9':
+			result |= 1 << uint(26+r-'0')
+		default:
+			result |= 1 << uint(36+r%28)
+		}
+	}
+
+	return result
+}
+
+// bigramSignature returns a bit set of the pairs of adjacent folded runes in s, every pair is hashed to one of the bits
+func bigramSignature(folded string) uint64 {
+	var result uint64
+	var prev rune = -1
+
+	for _, r := range folded {
+		if prev >= 0 {
+			result |= 1 << uint((prev*31+r)%64)
+		}
+		prev = r
+	}
+
+	return result
+}
+
+// initials returns the number of runes of the label an acronym can match, see matcher.acronymMatch
+func initials(label []rune) int {
+	count := 0
+
+	for j, r := range label {
+		if bonusAt(label, j) > 0 && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			count++
+		}
+	}
+
+	return count
+}
+
+// maxScore returns the best score a search of n runes can match the text with, see matcher.matchText. If the search
+// is not a subsequence of the text it can only match with typos.
+func (entry indexEntry) maxScore(n int, subsequence bool, typos bool) float64 {
+	m := entry.length
+
+	switch entry.text.kind {
+	case lookupDescription:
+		return descriptionWeight
+	case lookupAlias:
+		if !subsequence {
+			return 0.0
+		}
+		return aliasWeight * float64(n) / float64(m)
+	}
+
+	var best float64
+	if subsequence {
+		// All runs of the match start at a boundary
+		p := float64(n) / float64(m)
+		best = p + (1.0-p)*boundaryWeight
+
+		if n >= 2 && n <= entry.initials {
+			best = math.Max(best, acronymWeight*float64(n)/float64(entry.initials))
+		}
+	} else if typos {
+		// There is at least one typo
+		best = math.Min(typoWeight, typoWeight*float64(n-1)/float64(m))
+	}
+
+	if entry.text.kind == lookupKeyword {
+		best *= keywordWeight
+	}
+
+	return best
+}
+
+// indexSearch is a search of the index, it finds the candidates and ranks them by the best score they can match with
+// before it scores them
+type indexSearch struct {
+	m       *matcher
+	n       int
+	allowed int // The number of typos the search may contain
+
+	wanted        uint64
+	wantedWords   uint64
+	wantedBigrams uint64
+}
+
+// itemBound is the best rank an item can get for the search, its texts are candidates[first:last]
+type itemBound struct {
+	item           int
+	first, last    int
+	priority       int
+	pluginPriority int
+	score          float64
+}
+
+// textBound is the best score a candidate text can match with, and whether it is worth looking for typos in it
+type textBound struct {
+	score float64
+	typos bool
+}
+
+// check returns the best score the text can match the search with, 0.0 if it cannot match
+func (s *indexSearch) check(entry indexEntry) textBound {
+	typos := false
+
+	switch entry.text.kind {
+	case lookupLabel, lookupKeyword:
+		if bits.OnesCount64(s.wanted&^entry.signature) > s.allowed {
+			return textBound{}
+		}
+
+		// Only the texts that share enough pairs with the search are worth looking for typos
+		typos = s.allowed > 0 && bits.OnesCount64(s.wantedBigrams&^entry.bigrams) <= 3*s.allowed
+	case lookupDescription:
+		if s.wantedWords&^entry.signature != 0 {
+			return textBound{}
+		}
+	default:
+		if s.wanted&^entry.signature != 0 {
+			return textBound{}
+		}
+	}
+
+	var subsequence bool
+	switch entry.text.kind {
+	case lookupDescription:
+		subsequence = true
+	case lookupAlias:
+		subsequence = strings.HasPrefix(entry.text.folded, string(s.m.search))
+	default:
+		subsequence = isSubsequenceOf(s.m.search, entry.text.folded)
+	}
+
+	return textBound{score: entry.maxScore(s.n, subsequence, typos), typos: typos}
+}
+
+// search returns the best matching items of the plugin of the scope, or of all plugins if the scope is nil, ordered
+// by descending priority and score and limited to catalogResultLimit items. An item matches with the best of its
+// texts.
+//
+// The items are scored in the order of the best rank they can get, until the items that are left cannot rank above
+// the worst of the best items found so far. Only the items in the history get a bonus, they are always scored.
+func (x *catalogIndex) search(e *Engine, scope api.Plugin, search string) []SuggestItem {
+	m := newMatcher(search)
+	if len(m.search) == 0 {
+		return nil
+	}
+
+	s := indexSearch{
+		m:       m,
+		n:       len(m.search),
+		allowed: maxTypos(len(m.search)),
+
+		// Every typo can introduce at most one rune the text does not contain, and break at most three pairs of runes
+		// of the search, e.g. by swapping the middle runes of "abcd"
+		wanted:        signature(string(m.search)),
+		wantedWords:   signature(strings.Join(m.words, "")),
+		wantedBigrams: bigramSignature(string(m.search)),
+	}
+
+	priorities := make(map[api.Plugin]int, len(e.plugins))
+	for _, p := range e.plugins {
+		priorities[p] = e.optionsOf(p).Priority
+	}
+
+	history := e.history.Scores(search)
+	inHistory := x.historyItems(history)
+
+	candidates := x.candidates(m, s.allowed)
+	checks := make([]textBound, len(candidates))
+
+	// score returns the best score of the texts of the item
+	score := func(b itemBound) (float64, []Range) {
+		var best float64
+		var bestRanges []Range
+
+		for idx := b.first; idx < b.last; idx++ {
+			if checks[idx].score <= best {
+				continue
+			}
+
+			entry := x.entries[candidates[idx]]
+			if score, ranges := m.matchText(entry.text, checks[idx].typos); score > best {
+				best, bestRanges = score, ranges
+			}
+		}
+
+		return best, bestRanges
+	}
+
+	var result []SuggestItem
+	bounds := make(itemBounds, 0, len(candidates))
+
+	for first := 0; first < len(candidates); {
+		item := x.entries[candidates[first]].item
+		last := first + 1
+		for last < len(candidates) && x.entries[candidates[last]].item == item {
+			last++
+		}
+
+		b := itemBound{item: item, first: first, last: last}
+		first = last
+
+		ii := &x.items[item]
+		if scope != nil && ii.plugin != scope {
+			continue
+		}
+
+		for idx := b.first; idx < b.last; idx++ {
+			checks[idx] = s.check(x.entries[candidates[idx]])
+			b.score = math.Max(b.score, checks[idx].score)
+		}
+
+		if b.score == 0.0 {
+			continue
+		}
+
+		if inHistory[item] {
+			if score, ranges := score(b); score > 0.0 {
+				result = append(result, e.rankedWith(*ii, api.MatchFuzzy, history, score, ranges, 0, 0, priorities[ii.plugin]))
+			}
+			continue
+		}
+
+		if ii.Item.Score > 0.0 {
+			b.score = ii.Item.Score
+		}
+		b.score, b.priority = hinted(&ii.Item, b.score)
+		b.pluginPriority = priorities[ii.plugin]
+		bounds = append(bounds, b)
+	}
+
+	// The items outside the history are ranked by their match alone
+	var best suggestionHeap
+	heap.Init(&bounds)
+
+	for len(bounds) > 0 {
+		b := heap.Pop(&bounds).(itemBound)
+		if len(best) == catalogResultLimit && !b.ranksAbove(best[0]) {
+			// None of the items that are left can rank higher
+			break
+		}
+
+		score, ranges := score(b)
+		if score == 0.0 {
+			continue
+		}
+
+		ii := x.items[b.item]
+		suggestion := e.rankedWith(ii, api.MatchFuzzy, HistoryScores{}, score, ranges, 0, 0, b.pluginPriority)
+		if len(best) < catalogResultLimit {
+			heap.Push(&best, suggestion)
+		} else if ranksAbove(suggestion, best[0]) {
+			best[0] = suggestion
+			heap.Fix(&best, 0)
+		}
+	}
+
+	result = append(result, best...)
+	sort.SliceStable(result, func(i, j int) bool {
+		return ranksAbove(result[i], result[j])
+	})
+
+	if len(result) > catalogResultLimit {
+		result = result[:catalogResultLimit]
+	}
+
+	return result
+}
+
+// ranksAbove returns true if the item would be shown above the suggestion with its best rank
+func (b itemBound) ranksAbove(s SuggestItem) bool {
+	return ranksAbove(SuggestItem{Priority: b.priority, pluginPriority: b.pluginPriority, Score: b.score}, s)
+}
+
+// isSubsequenceOf returns true if the runes of s appear in the folded text in the same order
+func isSubsequenceOf(s []rune, folded string) bool {
+	si := 0
+	for _, r := range folded {
+		if si < len(s) && r == s[si] {
+			si++
+		}
+	}
+
+	return si == len(s)
+}
+
+// historyItems returns which items get a bonus from the history
+func (x *catalogIndex) historyItems(history HistoryScores) []bool {
+	result := make([]bool, len(x.items))
+
+	for _, idx := range x.frecencyItems(history) {
+		result[idx] = true
+	}
+
+	for identity := range history.affinity {
+		for _, idx := range x.identities[identity] {
+			result[idx] = true
+		}
+	}
+
+	return result
+}
+
+// frecencyItems returns the items with a frecency, the history rarely changes between searches so they are cached
+func (x *catalogIndex) frecencyItems(history HistoryScores) []int32 {
+	x.historyMutex.Lock()
+	defer x.historyMutex.Unlock()
+
+	if x.historyItemsCache == nil || x.historyGeneration != history.generation {
+		x.historyItemsCache = make([]int32, 0, len(history.frecency))
+		for identity := range history.frecency {
+			x.historyItemsCache = append(x.historyItemsCache, x.identities[identity]...)
+		}
+		x.historyGeneration = history.generation
+	}
+
+	return x.historyItemsCache
+}
+
+// candidates returns the entries, in ascending order, whose text contains the runes of the search. The description
+// only has to contain the runes of the words of the search. A search that may contain typos may miss as many runes as
+// it may contain typos.
+func (x *catalogIndex) candidates(m *matcher, allowed int) []int32 {
+	var runes []rune
+	seen := make(map[rune]bool)
+
+	collect := func(s []rune) {
+		for _, r := range s {
+			if !seen[r] {
+				seen[r] = true
+				runes = append(runes, r)
+			}
+		}
+	}
+
+	if allowed == 0 {
+		collect([]rune(strings.Join(m.words, "")))
+		if len(runes) == 0 {
+			collect(m.search)
+		}
+
+		return x.intersect(runes)
+	}
+
+	collect(m.search)
+
+	return x.containingAtLeast(runes, len(runes)-allowed)
+}
+
+// intersect returns the entries that contain all runes
+func (x *catalogIndex) intersect(runes []rune) []int32 {
+	lists := make([][]int32, 0, len(runes))
+	for _, r := range runes {
+		lists = append(lists, x.postings[r])
+	}
+
+	// Start with the shortest list, the result cannot be longer
+	sort.Slice(lists, func(i, j int) bool {
+		return len(lists[i]) < len(lists[j])
+	})
+
+	result := append([]int32(nil), lists[0]...)
+	for _, list := range lists[1:] {
+		kept := result[:0]
+		at := 0
+
+		for _, id := range result {
+			at += sort.Search(len(list)-at, func(k int) bool { return list[at+k] >= id })
+			if at == len(list) {
+				break
+			}
+			if list[at] == id {
+				kept = append(kept, id)
+			}
+		}
+
+		result = kept
+	}
+
+	return result
+}
+
+// containingAtLeast returns the entries that contain at least min of the runes
+func (x *catalogIndex) containingAtLeast(runes []rune, min int) []int32 {
+	counts := make([]uint8, len(x.entries))
+	for _, r := range runes {
+		for _, id := range x.postings[r] {
+			counts[id]++
+		}
+	}
+
+	var result []int32
+	for id, count := range counts {
+		if int(count) >= min {
+			result = append(result, int32(id))
+		}
+	}
+
+	return result
+}
+
+// ranksAbove returns true if a is shown above b
+func ranksAbove(a SuggestItem, b SuggestItem) bool {
+	if a.Priority != b.Priority {
+		return a.Priority > b.Priority
+	}
+
+	if a.pluginPriority != b.pluginPriority {
+		return a.pluginPriority > b.pluginPriority
+	}
+
+	return a.Score > b.Score
+}
+
+// itemBounds is a max-heap of the best ranks of items, the item that can rank highest is on top
+type itemBounds []itemBound
+
+func (h itemBounds) Len() int { return len(h) }
+func (h itemBounds) Less(i, j int) bool {
+	a, b := h[i], h[j]
+	if a.priority != b.priority {
+		return a.priority > b.priority
+	}
+	if a.pluginPriority != b.pluginPriority {
+		return a.pluginPriority > b.pluginPriority
+	}
+	return a.score > b.score
+}
+func (h itemBounds) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *itemBounds) Push(x interface{}) { *h = append(*h, x.(itemBound)) }
+
+func (h *itemBounds) Pop() interface{} {
+	old := *h
+	last := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return last
+}
+
+// suggestionHeap is a min-heap of suggestions, the lowest ranking suggestion is on top
+type suggestionHeap []SuggestItem
+
+func (h suggestionHeap) Len() int            { return len(h) }
+func (h suggestionHeap) Less(i, j int) bool  { return ranksAbove(h[j], h[i]) }
+func (h suggestionHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *suggestionHeap) Push(x interface{}) { *h = append(*h, x.(SuggestItem)) }
+
+func (h *suggestionHeap) Pop() interface{} {
+	old := *h
+	last := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return last
+}
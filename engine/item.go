@@ -0,0 +1,141 @@
+package engine
+
+import (
+	"image"
+
+	"go-keyboard-launcher/api"
+)
+
+type InternalItem struct {
+	Item       api.Item
+	lookupName string   // The label without case and diacritics
+	aliases    []string // Words the user configured to find the item by
+	plugin     api.Plugin
+	action     *api.Action // Set for the entries of an action menu, the action of the item the menu belongs to
+}
+
+// lookupKind determines how a text of an item is matched against the search
+type lookupKind int
+
+const (
+	lookupLabel       lookupKind = iota // Fuzzy, the matched runes are highlighted
+	lookupKeyword                       // Fuzzy, with keywordWeight
+	lookupDescription                   // Every word of the search starts a word, with descriptionWeight
+	lookupAlias                         // The alias starts with the search, with aliasWeight
+)
+
+// lookupText is a text an item can be found by
+type lookupText struct {
+	text   string
+	folded string
+	kind   lookupKind
+}
+
+type StackEntry struct {
+	item             InternalItem
+	actions          bool          // The entry shows the actions of the item instead of the suggestions of its plugin
+	scope            bool          // The entry limits the search to the plugin of its item, its item is not passed on
+	open             string        // The ID of a suggestion of the item to open as soon as it is suggested
+	searchText       string        // The text that was searched for
+	suggestItems     []SuggestItem // Then items that were suggested when pushed on the stack
+	suggestItemIndex int
+}
+
+func (s StackEntry) Item() InternalItem {
+	return s.item
+}
+
+// IsActionMenu returns true if the entry lists the actions of its item
+func (s StackEntry) IsActionMenu() bool {
+	return s.actions
+}
+
+// IsScope returns true if the entry limits the search to the plugin of its item
+func (s StackEntry) IsScope() bool {
+	return s.scope
+}
+
+type SuggestItem struct {
+	Item     InternalItem
+	Score    float64
+	Priority int     // Items with a higher priority rank above all items with a lower priority, regardless of score
+	Ranges   []Range // The runes of the label that matched the search, used to highlight them
+
+	pluginPriority int // The priority of the plugin of the item, see PluginOptions
+}
+
+const (
+	priorityNormal = iota
+	priorityTop
+	priorityFailure // Execution failures are shown above everything else
+)
+
+// hitBoost is added to the score of items with the api.HitBoost hint
+const hitBoost = 1.0
+
+func (i InternalItem) DisplayName() string {
+	return i.Item.Label
+}
+
+func (i InternalItem) Icon() *image.Image {
+	if i.Item.Icon != nil {
+		return i.Item.Icon
+	} else if i.plugin != nil && i.plugin.Icon() != nil {
+		return i.plugin.Icon()
+	} else {
+		return nil
+	}
+}
+
+// Identity returns a key that identifies the item across catalog rebuilds, searches and sessions. It is scoped by
+// the name of the plugin that provided the item, failures of the launcher itself have no plugin.
+func (i InternalItem) Identity() string {
+	if i.plugin == nil {
+		return i.Item.Key()
+	}
+
+	return i.plugin.Name() + ":" + i.Item.Key()
+}
+
+func (i InternalItem) Plugin() api.Plugin {
+	return i.plugin
+}
+
+func (i InternalItem) Description() string {
+	return i.Item.Description
+}
+
+// lookupTexts returns the texts the item can be found by, starting with its label
+func (i InternalItem) lookupTexts() []lookupText {
+	result := []lookupText{{text: i.Item.Label, folded: i.lookupName, kind: lookupLabel}}
+
+	for _, each := range i.Item.Keywords {
+		result = append(result, lookupText{text: each, folded: foldString(each), kind: lookupKeyword})
+	}
+
+	if len(i.Item.Description) > 0 {
+		result = append(result, lookupText{text: i.Item.Description, folded: foldString(i.Item.Description), kind: lookupDescription})
+	}
+
+	for _, each := range i.aliases {
+		result = append(result, lookupText{text: each, folded: foldString(each), kind: lookupAlias})
+	}
+
+	return result
+}
+
+func asInternalItem(each api.Item, plugin api.Plugin) InternalItem {
+	return InternalItem{
+		Item:       each,
+		lookupName: foldString(each.Label),
+		plugin:     plugin,
+	}
+}
+
+func collectItems(stack []StackEntry) []api.Item {
+	result := make([]api.Item, len(stack))
+	for idx, e := range stack {
+		result[idx] = e.item.Item
+	}
+	return result
+}
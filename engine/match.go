@@ -0,0 +1,543 @@
+package engine
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Range is a range of runes in a label that matched the search, from Start up to but not including End
+type Range struct {
+	Start int
+	End   int
+}
+
+// Bonuses for matching a rune at a certain position in the label, used to pick the best of all possible matches
+const (
+	bonusPath        = 4 // After a path separator, e.g. the "r" in "owner/repo"
+	bonusBoundary    = 3 // At the start of the label or of a word
+	bonusCamel       = 2 // At a hump of a camelCase word or the start of a number
+	bonusConsecutive = 4 // Right after the previously matched rune
+)
+
+// boundaryWeight is the part of the unmatched score that is awarded when all runs of a match start at a word boundary
+const boundaryWeight = 0.5
+
+// fold lower-cases the rune and removes its diacritics, e.g. 'É' becomes 'e'
+func fold(r rune) rune {
+	if r >= utf8.RuneSelf {
+		// The first rune of the canonical decomposition is the base character, the others are combining marks
+		r, _ = utf8.DecodeRuneInString(norm.NFD.String(string(r)))
+	}
+
+	return unicode.ToLower(r)
+}
+
+// foldString folds every rune of s, the result has the same number of runes as s
+func foldString(s string) string {
+	return strings.Map(fold, s)
+}
+
+// Matches returns true if all runes of search appear in input in the same order
+func Matches(search string, input string) bool {
+	return isSubsequence([]rune(foldString(search)), []rune(foldString(input)))
+}
+
+// isSubsequence returns true if the runes of s appear in l in the same order
+func isSubsequence(s []rune, l []rune) bool {
+	if len(s) == 0 {
+		return true
+	}
+
+	si := 0
+	for _, r := range l {
+		if r == s[si] {
+			si++
+			// Have we matched all search characters to the input string?
+			if si == len(s) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// MatchScore returns a float indicating how well a string is matched by input
+func MatchScore(search string, input string) float64 {
+	score, _ := FuzzyMatch(search, input)
+	return score
+}
+
+// FuzzyMatch matches the runes of search in order against the label, ignoring case and diacritics. Of all possible
+// matches it picks the one that prefers runs of consecutive runes and runs that start at word boundaries, camelCase
+// humps and path separators. It returns a score between 0.0 and 1.0 and the matched ranges of runes in the label, or
+// 0.0 if the label does not match.
+//
+// The score is the part of the label that is matched, reduced when the match is split into several runs, and raised
+// when the runs start at boundaries. A search that matches the initials of the words scores as an acronym when that
+// is better. A search that does not match at all may still match with a few typos, which scores lower.
+func FuzzyMatch(search string, label string) (float64, []Range) {
+	return newMatcher(search).match(label, true)
+}
+
+// matcher matches one search against many labels. It folds the search once and reuses its buffers between labels,
+// so it must not be used by several goroutines at once.
+type matcher struct {
+	search []rune
+	words  []string // The words of the folded search
+
+	label  []rune
+	folded []rune
+	bonus  []int
+	cells  []int
+}
+
+func newMatcher(search string) *matcher {
+	folded := foldString(search)
+	return &matcher{search: []rune(folded), words: words(folded)}
+}
+
+// matchItem matches the search against all texts of the item and returns the best weighted score. Only a match of
+// the label has ranges.
+func (x *matcher) matchItem(ii InternalItem) (float64, []Range) {
+	var best float64
+	var bestRanges []Range
+
+	for _, t := range ii.lookupTexts() {
+		if score, ranges := x.matchText(t, true); score > best {
+			best, bestRanges = score, ranges
+		}
+	}
+
+	return best, bestRanges
+}
+
+// matchText matches the search against a text of an item according to its kind, typos are only tolerated in the
+// label and keywords and only if typos is true
+func (x *matcher) matchText(t lookupText, typos bool) (float64, []Range) {
+	switch t.kind {
+	case lookupKeyword:
+		score, _ := x.match(t.text, typos)
+		return keywordWeight * score, nil
+	case lookupDescription:
+		return descriptionWeight * x.wordPrefixScore(t.folded), nil
+	case lookupAlias:
+		return aliasWeight * PrefixScore(string(x.search), t.folded), nil
+	default:
+		return x.match(t.text, typos)
+	}
+}
+
+// wordPrefixScore returns the part of the matched words of the folded text that is covered by the search, if every
+// word of the search starts a different word of the text
+func (x *matcher) wordPrefixScore(folded string) float64 {
+	if len(x.words) == 0 {
+		return 0.0
+	}
+
+	textWords := words(folded)
+	used := make([]bool, len(textWords))
+	searched, matched := 0, 0
+
+	for _, s := range x.words {
+		found := false
+		for idx, w := range textWords {
+			if !used[idx] && strings.HasPrefix(w, s) {
+				used[idx] = true
+				found = true
+				searched += utf8.RuneCountInString(s)
+				matched += utf8.RuneCountInString(w)
+				break
+			}
+		}
+
+		if !found {
+			return 0.0
+		}
+	}
+
+	return float64(searched) / float64(matched)
+}
+
+// match matches the search against the label like FuzzyMatch, typos are only tolerated if typos is true
+func (x *matcher) match(label string, typos bool) (float64, []Range) {
+	if len(x.search) == 0 {
+		return 0.0, nil
+	}
+
+	x.label = x.label[:0]
+	x.folded = x.folded[:0]
+	for _, r := range label {
+		x.label = append(x.label, r)
+		x.folded = append(x.folded, fold(r))
+	}
+
+	// Finding the best match is costly, most labels do not match at all
+	if len(x.search) > len(x.label) || !isSubsequence(x.search, x.folded) {
+		if !typos {
+			return 0.0, nil
+		}
+
+		return x.typoMatch()
+	}
+
+	x.bonus = x.bonus[:0]
+	for j := range x.label {
+		x.bonus = append(x.bonus, bonusAt(x.label, j))
+	}
+
+	score, ranges := x.subsequenceMatch()
+
+	if acronymScore, acronymRanges := x.acronymMatch(); acronymScore > score {
+		return acronymScore, acronymRanges
+	}
+
+	return score, ranges
+}
+
+// subsequenceMatch matches all runes of the search in order, the search must be a subsequence of the label
+func (x *matcher) subsequenceMatch() (float64, []Range) {
+	n := len(x.search)
+	m := len(x.label)
+
+	// Collect the runs of consecutive matches
+	ranges := toRanges(x.align(x.folded, x.bonus))
+	boundaries := 0
+
+	for _, r := range ranges {
+		if x.bonus[r.Start] > 0 {
+			boundaries++
+		}
+	}
+
+	runs := len(ranges)
+	matchPercentage := float64(n) / float64(m)
+	score := matchPercentage
+
+	if runs > 1 {
+		unmatchedPercentage := 1.0 - matchPercentage
+		correction := (1.0 - (1.0 / float64(runs))) * unmatchedPercentage * matchPercentage
+		score -= correction
+	}
+
+	score += (1.0 - score) * boundaryWeight * float64(boundaries) / float64(runs)
+
+	return score, ranges
+}
+
+// acronymWeight is the score of a search that matches all initials of a label
+const acronymWeight = 0.9
+
+// acronymMatch matches the runes of the search in order against the initials of the words of the label, e.g. "gak"
+// matches "go-anywhere-keyboard". The score is the part of the initials that is matched, a single rune is not an
+// acronym.
+func (x *matcher) acronymMatch() (float64, []Range) {
+	if len(x.search) < 2 {
+		return 0.0, nil
+	}
+
+	var initials []rune
+	var at []int
+
+	for j, r := range x.label {
+		if x.bonus[j] > 0 && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			initials = append(initials, x.folded[j])
+			at = append(at, j)
+		}
+	}
+
+	if len(x.search) > len(initials) || !isSubsequence(x.search, initials) {
+		return 0.0, nil
+	}
+
+	// Every initial has a bonus, so only runs of consecutive initials make a difference
+	positions := x.align(initials, make([]int, len(initials)))
+	for idx, p := range positions {
+		positions[idx] = at[p]
+	}
+
+	return acronymWeight * float64(len(x.search)) / float64(len(initials)), toRanges(positions)
+}
+
+// Weights of matching a text of an item other than its label, the label matches with weight 1.0
+const (
+	keywordWeight     = 0.8
+	descriptionWeight = 0.5
+	aliasWeight       = 1.0
+)
+
+// typoWeight is the part of the score a match with typos gets compared to the same match without typos
+const typoWeight = 0.5
+
+// maxTypos returns how many typos a search of n runes may contain, short searches must be typed correctly
+func maxTypos(n int) int {
+	switch {
+	case n < 4:
+		return 0
+	case n < 8:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// typoMatch finds the part of the label that is closest to the search, counting inserted, deleted, replaced and
+// swapped runes as typos. The label matches if the number of typos is small enough for the length of the search.
+func (x *matcher) typoMatch() (float64, []Range) {
+	s := x.search
+	l := x.folded
+	n := len(s)
+	m := len(l)
+	allowed := maxTypos(n)
+
+	if allowed == 0 || m == 0 {
+		return 0.0, nil
+	}
+
+	// Row i of dist holds the least number of typos to match s[:i] with a part of the label ending at j, the part
+	// starts at the same column of start. Only the last three rows are kept.
+	cells := x.scratch(6 * (m + 1))
+
+	var dist [3][]int
+	var start [3][]int
+	for k := range dist {
+		dist[k] = cells[2*k*(m+1) : (2*k+1)*(m+1)]
+		start[k] = cells[(2*k+1)*(m+1) : (2*k+2)*(m+1)]
+	}
+
+	// The part can start anywhere
+	for j := 0; j <= m; j++ {
+		dist[0][j] = 0
+		start[0][j] = j
+	}
+
+	lastMin := 0
+
+	for i := 1; i <= n; i++ {
+		cur, prev, prev2 := dist[i%3], dist[(i-1)%3], dist[(i+1)%3]
+		curStart, prevStart, prev2Start := start[i%3], start[(i-1)%3], start[(i+1)%3]
+
+		cur[0], curStart[0] = i, 0
+		rowMin := i
+
+		for j := 1; j <= m; j++ {
+			cost := 1
+			if s[i-1] == l[j-1] {
+				cost = 0
+			}
+
+			d, from := prev[j-1]+cost, prevStart[j-1]
+
+			if v := prev[j] + 1; v < d {
+				d, from = v, prevStart[j]
+			}
+			if v := cur[j-1] + 1; v < d {
+				d, from = v, curStart[j-1]
+			}
+			if i > 1 && j > 1 && s[i-1] == l[j-2] && s[i-2] == l[j-1] {
+				if v := prev2[j-2] + 1; v < d {
+					d, from = v, prev2Start[j-2]
+				}
+			}
+
+			cur[j], curStart[j] = d, from
+			if d < rowMin {
+				rowMin = d
+			}
+		}
+
+		// The following rows are derived from the last two, so they cannot get below their minimum
+		if rowMin > allowed && lastMin > allowed {
+			return 0.0, nil
+		}
+		lastMin = rowMin
+	}
+
+	last, lastStart := dist[n%3], start[n%3]
+
+	// Pick the end of the closest part, the longest one on a tie so a swapped last rune is included
+	end := 1
+	for j := 2; j <= m; j++ {
+		if last[j] <= last[end] {
+			end = j
+		}
+	}
+
+	typos := last[end]
+	if typos > allowed || lastStart[end] == end {
+		return 0.0, nil
+	}
+
+	score := typoWeight * float64(n-typos) / float64(m)
+	if score > typoWeight {
+		score = typoWeight
+	}
+
+	return score, []Range{{Start: lastStart[end], End: end}}
+}
+
+// toRanges combines the positions of matched runes into ranges of consecutive runes
+func toRanges(positions []int) []Range {
+	var ranges []Range
+
+	for _, p := range positions {
+		if len(ranges) > 0 && ranges[len(ranges)-1].End == p {
+			ranges[len(ranges)-1].End++
+			continue
+		}
+
+		ranges = append(ranges, Range{Start: p, End: p + 1})
+	}
+
+	return ranges
+}
+
+// align returns the positions in the folded runes l at which the runes of the search are matched, choosing the
+// positions with the highest total bonus. The search must be a subsequence of l.
+func (x *matcher) align(l []rune, bonus []int) []int {
+	const none = -1 << 30
+
+	s := x.search
+	n := len(s)
+	m := len(l)
+
+	// best[i*m+j] is the highest bonus of matching s[:i+1] with s[i] at l[j], from[i*m+j] the position of s[i-1]
+	cells := x.scratch(2 * n * m)
+	best := cells[:n*m]
+	from := cells[n*m:]
+
+	for i := 0; i < n; i++ {
+		row := best[i*m : (i+1)*m]
+
+		// The best position of the previous rune before j-1, which does not continue a run
+		prefixBest := none
+		prefixFrom := -1
+
+		for j := 0; j < m; j++ {
+			row[j] = none
+
+			if i > 0 && j >= 2 && best[(i-1)*m+j-2] > prefixBest {
+				prefixBest = best[(i-1)*m+j-2]
+				prefixFrom = j - 2
+			}
+
+			if l[j] != s[i] {
+				continue
+			}
+
+			if i == 0 {
+				row[j] = bonus[j]
+				continue
+			}
+
+			if prefixBest != none {
+				row[j] = prefixBest + bonus[j]
+				from[i*m+j] = prefixFrom
+			}
+
+			if j >= 1 && best[(i-1)*m+j-1] != none && best[(i-1)*m+j-1]+bonusConsecutive >= row[j] {
+				row[j] = best[(i-1)*m+j-1] + bonusConsecutive
+				from[i*m+j] = j - 1
+			}
+		}
+	}
+
+	// Pick the end of the best match, the earliest one on a tie
+	last := best[(n-1)*m:]
+	end := -1
+	for j := 0; j < m; j++ {
+		if last[j] != none && (end < 0 || last[j] > last[end]) {
+			end = j
+		}
+	}
+
+	positions := make([]int, n)
+	for i := n - 1; i >= 0; i-- {
+		positions[i] = end
+		end = from[i*m+end]
+	}
+
+	return positions
+}
+
+// scratch returns a buffer of at least size cells, its contents are undefined
+func (x *matcher) scratch(size int) []int {
+	if cap(x.cells) < size {
+		x.cells = make([]int, size)
+	}
+
+	return x.cells[:size]
+}
+
+// bonusAt returns the bonus for matching the rune at position j of the label
+func bonusAt(l []rune, j int) int {
+	if j == 0 {
+		return bonusBoundary
+	}
+
+	prev := l[j-1]
+	cur := l[j]
+
+	switch {
+	case prev == '/' || prev == '\\':
+		return bonusPath
+	case !unicode.IsLetter(prev) && !unicode.IsDigit(prev):
+		if unicode.IsLetter(cur) || unicode.IsDigit(cur) {
+			return bonusBoundary
+		}
+	case unicode.IsLower(prev) && unicode.IsUpper(cur):
+		return bonusCamel
+	case !unicode.IsDigit(prev) && unicode.IsDigit(cur):
+		return bonusCamel
+	}
+
+	return 0
+}
+
+// PrefixScore returns how well input is matched by search when input must start with search
+func PrefixScore(search string, input string) float64 {
+	search = foldString(search)
+	input = foldString(input)
+
+	if len(input) == 0 || !strings.HasPrefix(input, search) {
+		return 0.0
+	}
+
+	return float64(utf8.RuneCountInString(search)) / float64(utf8.RuneCountInString(input))
+}
+
+// WordScore returns how well input is matched by search when every word of search must be a word of input
+func WordScore(search string, input string) float64 {
+	inputWords := words(foldString(input))
+	matched := 0
+
+	for _, s := range words(foldString(search)) {
+		found := false
+		for _, i := range inputWords {
+			if s == i {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return 0.0
+		}
+		matched += utf8.RuneCountInString(s)
+	}
+
+	if matched == 0 {
+		return 0.0
+	}
+
+	return float64(matched) / float64(utf8.RuneCountInString(input))
+}
+
+func words(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
@@ -0,0 +1,304 @@
+package engine
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"testing"
+
+	"go-keyboard-launcher/api"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_MatchEmptySearch(t *testing.T) {
+	assert.True(t, Matches("", "Hallo"))
+}
+
+func Test_MatchDifferentPositions(t *testing.T) {
+	assert.True(t, Matches("wi", strings.ToLower("whimsical")))
+	assert.False(t, Matches("wi", strings.ToLower("incognito")))
+}
+
+func Test_MatchLongerSuffix(t *testing.T) {
+	assert.True(t, Matches("ial", "mercurial"))
+	assert.False(t, Matches("ials", "mercurial"))
+}
+
+func Test_MatchShortCircuit(t *testing.T) {
+	assert.False(t, Matches("aap", "a"))
+	assert.False(t, Matches("abcdefg", "_______abc"))
+}
+
+func Test_MatchExact(t *testing.T) {
+	assert.True(t, Matches("aap", "aap"))
+}
+
+func Test_MatchBoundaries(t *testing.T) {
+	assert.True(t, Matches("ae", "abcde"))
+}
+
+func Test_MatchScore_Exact(t *testing.T) {
+	assert.Equal(t, MatchScore("wi", "wi"), 1.0)
+	assert.Equal(t, MatchScore("bc", "abcd"), 0.5)
+
+	// Matches at the start of the label get half of the unmatched part as a bonus
+	assert.Equal(t, MatchScore("abc", "abcd"), 0.875)
+	assert.Equal(t, MatchScore("abc", "abcdefghijklmnopqrst"), 0.575)
+	assert.Equal(t, MatchScore("acd", "abcdefghijklmnopqrst"), 0.3146875)
+	assert.Equal(t, MatchScore("ace", "abcdefghijklmnopqrst"), 0.22083333333333333)
+
+	assert.Equal(t, MatchScore("a", "abcd"), 0.625)
+
+	assert.Equal(t, MatchScore("x", "y"), 0.0)
+
+	print(MatchScore("abcdefghijlmnop", "MaxxAudio Pro by Waves - Audio instelling voor luidsprekers"))
+}
+
+func Test_PrefixScore(t *testing.T) {
+	assert.Equal(t, 1.0, PrefixScore("abcd", "abcd"))
+	assert.Equal(t, 0.5, PrefixScore("ab", "abcd"))
+	assert.Equal(t, 0.0, PrefixScore("bc", "abcd"))
+	assert.Equal(t, 0.0, PrefixScore("a", ""))
+}
+
+func Test_WordScore(t *testing.T) {
+	assert.Equal(t, 4.0/13.0, WordScore("pull", "pull-requests"))
+	assert.Equal(t, 0.0, WordScore("pul", "pull-requests"))
+	assert.Equal(t, 12.0/13.0, WordScore("requests pull", "pull-requests"))
+	assert.Equal(t, 0.0, WordScore("pull tags", "pull-requests"))
+	assert.Equal(t, 0.0, WordScore(" ", "pull-requests"))
+}
+
+func Test_MatchUnicode(t *testing.T) {
+	assert.True(t, Matches("cafe", "Café"))
+	assert.True(t, Matches("émi", "Emile"))
+	assert.True(t, Matches("здр", "Здравствуйте"))
+	assert.False(t, Matches("ab", "ä"))
+
+	// Runes are counted instead of bytes
+	assert.Equal(t, 1.0, MatchScore("cafe", "Café"))
+	assert.Equal(t, 0.5, PrefixScore("ca", "Café"))
+	assert.Equal(t, 11.0/12.0, WordScore("creme brulee", "Crème Brûlée"))
+}
+
+func Test_FuzzyMatch(t *testing.T) {
+	tests := []struct {
+		search string
+		label  string
+		ranges []Range
+	}{
+		// Consecutive runes are preferred over scattered ones
+		{"abc", "xa-b-c abc", []Range{{7, 10}}},
+		// Word starts, camelCase humps and path separators are preferred over the middle of words
+		{"gr", "github/go-repo", []Range{{7, 8}, {10, 11}}},
+		{"lr", "go-keyboard-launcher/README", []Range{{12, 13}, {21, 22}}},
+		{"gh", "fetchGitHub", []Range{{5, 6}, {8, 9}}},
+		{"v2", "participle/v2", []Range{{11, 13}}},
+		// Ranges are counted in runes
+		{"bru", "Crème Brûlée", []Range{{6, 9}}},
+		// Acronyms match the initials of words
+		{"gak", "go-anywhere-keyboard", []Range{{0, 1}, {3, 4}, {12, 13}}},
+		{"gal", "go-keyboard-launcher/go-anywhere-launcher", []Range{{21, 22}, {24, 25}, {33, 34}}},
+		{"gah", "GoAnywhereHotkey", []Range{{0, 1}, {2, 3}, {10, 11}}},
+		// Typos are tolerated in longer searches
+		{"githbu", "github", []Range{{0, 6}}},
+		{"githbu", "go-anywhere/github", []Range{{12, 18}}},
+		{"keybaord", "go-keyboard-launcher", []Range{{3, 11}}},
+		{"lanucher", "Launcher", []Range{{0, 8}}},
+	}
+
+	for _, test := range tests {
+		score, ranges := FuzzyMatch(test.search, test.label)
+		assert.Greater(t, score, 0.0, test.label)
+		assert.Equal(t, test.ranges, ranges, "%s in %s", test.search, test.label)
+	}
+
+	misses := []struct {
+		search string
+		label  string
+	}{
+		{"xyz", "github/go-repo"},
+		// Short searches must be typed correctly
+		{"gti", "git"},
+		// Too many typos for the length of the search
+		{"gthbu", "github"},
+		{"abcdef", "github"},
+	}
+
+	for _, test := range misses {
+		score, ranges := FuzzyMatch(test.search, test.label)
+		assert.Equal(t, 0.0, score, "%s in %s", test.search, test.label)
+		assert.Nil(t, ranges)
+	}
+}
+
+func Test_FuzzyMatchRanking(t *testing.T) {
+	tests := []struct {
+		search string
+		better string
+		worse  string
+	}{
+		// A match at a word start ranks above the same match in the middle of a word
+		{"rep", "go/repo", "go/prep"},
+		// An acronym ranks above a scattered match
+		{"gak", "go-anywhere-keyboard", "gerakan"},
+		// An exact subsequence ranks above a match with typos
+		{"github", "github", "githbu"},
+		{"launcher", "launcher", "go-anywhere-lanucher"},
+	}
+
+	for _, test := range tests {
+		assert.Greater(t, MatchScore(test.search, test.better), MatchScore(test.search, test.worse),
+			"%s should match %s better than %s", test.search, test.better, test.worse)
+	}
+}
+
+func Test_MatchItem(t *testing.T) {
+	ii := InternalItem{
+		Item:       api.Item{Label: "go-proxy", Description: "A caching proxy for Go modules", Keywords: []string{"gomods"}},
+		lookupName: "go-proxy",
+		aliases:    []string{"mods"},
+	}
+
+	tests := []struct {
+		search string
+		score  float64
+		ranges []Range
+	}{
+		{"prox", 0.75, []Range{{3, 7}}},
+		{"gomods", keywordWeight, nil},
+		{"cach mod", descriptionWeight * 7.0 / 14.0, nil},
+		{"mo", aliasWeight * 0.5, nil},
+		{"modules caching", descriptionWeight, nil},
+		{"caching caching", 0.0, nil},
+		{"xyz", 0.0, nil},
+	}
+
+	for _, test := range tests {
+		score, ranges := newMatcher(test.search).matchItem(ii)
+		assert.InDelta(t, test.score, score, 0.0001, test.search)
+		assert.Equal(t, test.ranges, ranges, test.search)
+	}
+}
+
+func Test_CatalogIndexSearch(t *testing.T) {
+	e := benchmarkEngine(5000)
+	benchmarkHistory(e, 500)
+	index := e.catalogIndex()
+
+	for _, search := range []string{"g", "gak", "launcher", "lanucher", "xyz"} {
+		var linear []SuggestItem
+		for _, each := range index.items {
+			if s, ok := e.suggestion(each, api.MatchFuzzy, search, e.History().Scores(search), 0, 0); ok {
+				linear = append(linear, s)
+			}
+		}
+
+		sort.SliceStable(linear, func(i, j int) bool {
+			return ranksAbove(linear[i], linear[j])
+		})
+
+		result := index.search(e, nil, search)
+		assert.LessOrEqual(t, len(result), catalogResultLimit, search)
+		assert.Equal(t, len(linear) > 0, len(result) > 0, search)
+
+		// The index skips items that cannot match, but finds the same scores as scoring every item
+		for idx, s := range result {
+			assert.Equal(t, linear[idx].Score, s.Score, search)
+		}
+	}
+}
+
+// benchmarkLabels returns n labels that look like repository names, e.g. "kernel-labs/go-search-proxy"
+func benchmarkLabels(n int) []string {
+	words := []string{
+		"go", "anywhere", "keyboard", "launcher", "github", "search", "proxy", "kernel", "labs", "desktop", "entry",
+		"config", "server", "client", "api", "docs", "web", "app", "tools", "infra", "data", "stream", "cache",
+		"index", "router", "plugin", "theme", "icons", "shell", "script", "build", "deploy", "test", "bench",
+	}
+
+	r := rand.New(rand.NewSource(1))
+	word := func() string { return words[r.Intn(len(words))] }
+
+	labels := make([]string, n)
+	for idx := range labels {
+		labels[idx] = fmt.Sprintf("%s-%s/%s-%s-%s%d", word(), word(), word(), word(), word(), idx%100)
+	}
+
+	return labels
+}
+
+func benchmarkEngine(n int) *Engine {
+	p := newTestPlugin()
+	p.items = nil
+
+	for _, label := range benchmarkLabels(n) {
+		p.items = append(p.items, api.Item{ID: label, Label: label, Category: api.User})
+	}
+
+	return newTestEngine(p)
+}
+
+// benchmarkHistory records n executions of random items of the catalog, for random prefixes of their label
+func benchmarkHistory(e *Engine, n int) {
+	r := rand.New(rand.NewSource(2))
+	items := e.catalog()
+
+	for i := 0; i < n; i++ {
+		item := items[r.Intn(len(items))]
+		e.History().Record(item.Identity(), item.Item.Label[:1+r.Intn(8)])
+	}
+}
+
+func BenchmarkFuzzyMatch(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		FuzzyMatch("gak", "kernel-labs/go-anywhere-keyboard42")
+	}
+}
+
+func BenchmarkCatalogSearch_100k(b *testing.B) {
+	e := benchmarkEngine(100000)
+	index := e.catalogIndex()
+
+	for _, search := range []string{"g", "gak", "launcher", "lanucher", "xyz"} {
+		b.Run(search, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				index.search(e, nil, search)
+			}
+		})
+	}
+}
+
+// BenchmarkCatalogSearch_100kHistory searches with a history as large as the pruned history of the launcher, which
+// gives a bonus to the items that were executed
+func BenchmarkCatalogSearch_100kHistory(b *testing.B) {
+	e := benchmarkEngine(100000)
+	benchmarkHistory(e, 5000)
+	index := e.catalogIndex()
+
+	for _, search := range []string{"g", "gak", "launcher", "lanucher", "xyz"} {
+		b.Run(search, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				index.search(e, nil, search)
+			}
+		})
+	}
+}
+
+// BenchmarkCatalogSearch_100kLinear scores every item, as a baseline for the index
+func BenchmarkCatalogSearch_100kLinear(b *testing.B) {
+	e := benchmarkEngine(100000)
+	items := e.catalog()
+
+	for _, search := range []string{"g", "gak", "launcher", "lanucher", "xyz"} {
+		b.Run(search, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				for _, each := range items {
+					e.suggestion(each, api.MatchFuzzy, search, HistoryScores{}, 0, 0)
+				}
+			}
+		})
+	}
+}
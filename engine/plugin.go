@@ -0,0 +1,213 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go-keyboard-launcher/api"
+)
+
+// PluginOptions control how the engine invokes a plugin
+type PluginOptions struct {
+	SuggestTimeout time.Duration // Deadline of a single Suggest call
+	CatalogTimeout time.Duration // Deadline of building the catalog
+	ExecuteTimeout time.Duration // Deadline of executing an item
+
+	RefreshInterval time.Duration // How often the catalog is rebuilt in the background
+
+	// Priority ranks the items of the plugin above the items of plugins with a lower priority that have the same hit
+	// hint, regardless of their scores. The default is 0.
+	Priority int
+}
+
+// DefaultPluginOptions are used for plugins without options, and for the options that are left zero
+var DefaultPluginOptions = PluginOptions{
+	SuggestTimeout: 5 * time.Second,
+	CatalogTimeout: 2 * time.Minute,
+	ExecuteTimeout: 30 * time.Second,
+
+	RefreshInterval: time.Hour,
+}
+
+// SetPluginOptions sets the options of the plugin with the given name
+func (e *Engine) SetPluginOptions(name string, o PluginOptions) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.pluginOptions[name] = o
+}
+
+// ConfigurePlugin lets the plugin load its configuration with the loader, see api.Plugin. A catalog that the plugin
+// is building with its previous configuration is cancelled, the caller refreshes the catalog afterwards.
+func (e *Engine) ConfigurePlugin(p api.Plugin, load func(interface{}) error) {
+	e.mutex.Lock()
+	cancel := e.catalogCancels[p.Name()]
+	e.mutex.Unlock()
+
+	if cancel != nil {
+		cancel()
+	}
+
+	p.LoadConfig(load)
+}
+
+func (e *Engine) optionsOf(p api.Plugin) PluginOptions {
+	e.mutex.Lock()
+	o := e.pluginOptions[p.Name()]
+	e.mutex.Unlock()
+
+	if o.SuggestTimeout <= 0 {
+		o.SuggestTimeout = DefaultPluginOptions.SuggestTimeout
+	}
+	if o.CatalogTimeout <= 0 {
+		o.CatalogTimeout = DefaultPluginOptions.CatalogTimeout
+	}
+	if o.ExecuteTimeout <= 0 {
+		o.ExecuteTimeout = DefaultPluginOptions.ExecuteTimeout
+	}
+	if o.RefreshInterval <= 0 {
+		o.RefreshInterval = DefaultPluginOptions.RefreshInterval
+	}
+
+	return o
+}
+
+// guard runs f in its own goroutine and waits until it is done or the context expires. A panic in f is recovered
+// and returned as an error, so a misbehaving plugin cannot take down the launcher.
+func guard(ctx context.Context, f func() error) error {
+	done := make(chan error, 1)
+
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("plugin panicked: %v", r)
+			}
+		}()
+
+		done <- f()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		if ctx.Err() == context.DeadlineExceeded {
+			return fmt.Errorf("timed out")
+		}
+		return nil
+	}
+}
+
+// catalogPlugin lets the plugin build its catalog within its deadline. Batches that are delivered after the deadline
+// are dropped. A plugin that is still building its catalog after the deadline is not asked again until it is done.
+// Loading a configuration cancels the catalog, the plugin is waited for until it returns or the deadline expires.
+func (e *Engine) catalogPlugin(p api.Plugin, callback api.CatalogCallback) error {
+	deadline, cancelDeadline := context.WithTimeout(context.Background(), e.optionsOf(p).CatalogTimeout)
+	defer cancelDeadline()
+	ctx, cancel := context.WithCancel(deadline)
+	defer cancel()
+
+	e.mutex.Lock()
+	if e.cataloging[p.Name()] {
+		e.mutex.Unlock()
+		return errCatalogRunning
+	}
+	e.cataloging[p.Name()] = true
+	e.catalogCancels[p.Name()] = cancel
+	e.mutex.Unlock()
+
+	defer func() {
+		e.mutex.Lock()
+		delete(e.catalogCancels, p.Name())
+		e.mutex.Unlock()
+	}()
+
+	err := guard(deadline, func() error {
+		defer func() {
+			e.mutex.Lock()
+			delete(e.cataloging, p.Name())
+			e.mutex.Unlock()
+		}()
+
+		return p.Catalog(ctx, func(items []api.Item) {
+			if ctx.Err() != nil {
+				return
+			}
+			callback(items)
+		})
+	})
+
+	// The items of a cancelled catalog are incomplete, even if the plugin returned without an error
+	if deadline.Err() == nil && ctx.Err() != nil {
+		return errCatalogCancelled
+	}
+
+	return err
+}
+
+var (
+	// errCatalogRunning is returned when the plugin is asked for its catalog before it finished building the previous
+	// one
+	errCatalogRunning = errors.New("still building the previous catalog")
+
+	// errCatalogCancelled is returned when the configuration of the plugin changed while it built its catalog
+	errCatalogCancelled = errors.New("cancelled by a new configuration")
+)
+
+// suggest asks the plugin for suggestions within its deadline. Suggestions that are delivered after the deadline or
+// after the plugin returned are dropped, a timeout or panic is reported as an error item.
+func (e *Engine) suggest(ctx context.Context, generation uint64, p api.Plugin, input string, chain []api.Item, callback api.SuggestionCallback) {
+	ctx, cancel := context.WithTimeout(ctx, e.optionsOf(p).SuggestTimeout)
+	defer cancel()
+
+	err := guard(ctx, func() error {
+		p.Suggest(ctx, input, chain, func(items []api.Item, match api.Match) {
+			if ctx.Err() != nil {
+				return
+			}
+			callback(items, match)
+		})
+		return nil
+	})
+
+	if err != nil {
+		e.log.Error(fmt.Sprintf("Plugin %s failed to suggest", p.Name()), "error", err)
+		e.addSuggestions(generation, []SuggestItem{errorSuggestion(p, err)})
+	}
+}
+
+// executeItem executes the action of the item within the deadline of the context. The default action of File and
+// Url items, and the built-in actions, are handled by the launcher itself, other actions by the plugin that provided
+// the item.
+func (e *Engine) executeItem(ctx context.Context, i InternalItem, action string) error {
+	return guard(ctx, func() error {
+		if action == ActionCopyTarget {
+			return api.CopyToClipboard(i.Item.Target)
+		}
+
+		if action == api.DefaultAction {
+			switch i.Item.Category {
+			case api.File:
+				return executeFile(ctx, i.Item)
+			case api.Url:
+				return executeUrl(ctx, i.Item)
+			}
+		}
+
+		if i.Item.Category >= api.User || action != api.DefaultAction {
+			return i.plugin.Execute(ctx, i.Item, action)
+		}
+
+		return fmt.Errorf("cannot handle items of category %d", i.Item.Category)
+	})
+}
+
+// errorSuggestion reports a failure of the plugin as an item at the bottom of the suggestions
+func errorSuggestion(p api.Plugin, err error) SuggestItem {
+	return SuggestItem{
+		Item:  asInternalItem(api.NewErrorItem(p.Name(), err), p),
+		Score: 0.0,
+	}
+}
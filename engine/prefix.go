@@ -0,0 +1,112 @@
+package engine
+
+import (
+	"sort"
+	"strings"
+
+	"go-keyboard-launcher/api"
+)
+
+// SetPrefixes configures the keywords that route a query to a single plugin. A query that starts with a keyword
+// followed by a space is sent to the target of the keyword only. The target is either the name of a plugin, which
+// limits the search to that plugin, or the identity of a catalog item (e.g. "str:base64"), which is pushed on the
+// stack as if the user had selected it.
+func (e *Engine) SetPrefixes(prefixes map[string]string) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.prefixes = make(map[string]string, len(prefixes))
+	for keyword, target := range prefixes {
+		keyword = strings.ToLower(strings.TrimSpace(keyword))
+		if len(keyword) > 0 {
+			e.prefixes[keyword] = target
+		}
+	}
+}
+
+// routePrefix pushes the target of the keyword the input starts with, and returns the remainder of the input. It
+// returns false if the input does not start with a keyword, or if the stack is not empty.
+func (e *Engine) routePrefix(input string) (string, bool) {
+	e.mutex.Lock()
+	if len(e.itemStack) > 0 {
+		e.mutex.Unlock()
+		return "", false
+	}
+
+	// Prefer the longest keyword, so "gh" and "ghe" can be used together
+	keywords := make([]string, 0, len(e.prefixes))
+	for keyword := range e.prefixes {
+		keywords = append(keywords, keyword)
+	}
+	prefixes := e.prefixes
+	e.mutex.Unlock()
+
+	sort.Slice(keywords, func(i, j int) bool {
+		return len(keywords[i]) > len(keywords[j])
+	})
+
+	// The keyword is compared with the start of the input as typed, since lowercasing may change the length of the
+	// input in bytes
+	for _, keyword := range keywords {
+		n := len(keyword)
+		if len(input) <= n || input[n] != ' ' || !strings.EqualFold(input[:n], keyword) {
+			continue
+		}
+
+		item, scope, ok := e.prefixTarget(prefixes[keyword])
+		if !ok {
+			e.log.Warn("Unknown target of search prefix", "keyword", keyword, "target", prefixes[keyword])
+			continue
+		}
+
+		e.mutex.Lock()
+		e.itemStack = append(e.itemStack, StackEntry{
+			item:             item,
+			scope:            scope,
+			searchText:       input[:n],
+			suggestItems:     e.suggestItems,
+			suggestItemIndex: e.suggestItemIndex,
+		})
+		e.mutex.Unlock()
+
+		e.notify(EventStackChanged)
+
+		return input[n+1:], true
+	}
+
+	return "", false
+}
+
+// prefixTarget resolves the target of a keyword to a plugin scope or to a catalog item
+func (e *Engine) prefixTarget(target string) (InternalItem, bool, bool) {
+	if p, ok := e.PluginByName(target); ok {
+		return scopeItem(p), true, true
+	}
+
+	if item, ok := e.catalogItem(target); ok {
+		return item, false, true
+	}
+
+	return InternalItem{}, false, false
+}
+
+// scopeItem returns the item on the stack that limits the search to the plugin
+func scopeItem(p api.Plugin) InternalItem {
+	return asInternalItem(api.Item{
+		ID:       "scope:" + p.Name(),
+		Label:    p.Name(),
+		Category: api.Keyword,
+		ArgsHint: api.Forbidden,
+	}, p)
+}
+
+// catalogItem returns the item of the catalog with the identity, e.g. "str:base64"
+func (e *Engine) catalogItem(identity string) (InternalItem, bool) {
+	for _, each := range e.catalog() {
+		if each.Identity() == identity {
+			return each, true
+		}
+	}
+
+	return InternalItem{}, false
+}
@@ -11,10 +11,13 @@ require (
 	github.com/google/go-querystring v1.1.0
 	github.com/hashicorp/go-hclog v1.3.1
 	github.com/joho/godotenv v1.4.0
+	github.com/srwiley/oksvg v0.0.0-20221011165216-be6e8873101c
+	github.com/srwiley/rasterx v0.0.0-20210519020934-456a8d69b780
 	github.com/stretchr/testify v1.8.0
 	golang.design/x/clipboard v0.6.2
-	golang.org/x/sys v0.0.0-20220825204002-c680a09ffe64
-	golang.org/x/text v0.3.7
+	golang.org/x/image v0.0.0-20220722155232-062f8c9fd539
+	golang.org/x/sys v0.13.0
+	golang.org/x/text v0.13.0
 )
 
 require (
@@ -36,8 +39,8 @@ require (
 	github.com/oxtoacart/bpool v0.0.0-20190530202638-03653db5a59c // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	golang.org/x/exp v0.0.0-20210722180016-6781d3edade3 // indirect
-	golang.org/x/image v0.0.0-20220722155232-062f8c9fd539 // indirect
 	golang.org/x/mobile v0.0.0-20210716004757-34ab1303b554 // indirect
+	golang.org/x/net v0.17.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
 
@@ -1,14 +1,22 @@
 package main
 
 import (
+	"context"
 	_ "embed"
+	"flag"
 	"log"
 	"os"
 
 	"go-keyboard-launcher/api"
-	"go-keyboard-launcher/plugin/expr"
-	"go-keyboard-launcher/plugin/github"
-	"go-keyboard-launcher/plugin/str"
+
+	// The plugins register themselves
+	_ "go-keyboard-launcher/plugin/expr"
+	_ "go-keyboard-launcher/plugin/github"
+	_ "go-keyboard-launcher/plugin/process"
+	_ "go-keyboard-launcher/plugin/scripts"
+	_ "go-keyboard-launcher/plugin/startmenu"
+	_ "go-keyboard-launcher/plugin/str"
+	_ "go-keyboard-launcher/plugin/xdgapps"
 
 	"gioui.org/app"
 	"github.com/getlantern/systray"
@@ -26,14 +34,15 @@ func (a *App) Hide() {
 }
 
 func main() {
-	plugins := []api.Plugin{
-		//&startmenu.Plugin{},
-		&expr.Plugin{},
-		&str.Plugin{},
-		&github.Plugin{},
+	checkConfig := flag.Bool("check-config", false, "check the configuration file for problems and exit")
+	flag.Parse()
+
+	a := NewApp()
+
+	if *checkConfig {
+		os.Exit(a.CheckConfiguration())
 	}
 
-	a := NewApp(plugins)
 	err := a.ReadConfiguration()
 
 	if err != nil {
@@ -41,10 +50,12 @@ func main() {
 		return
 	}
 
+	a.LoadHistory()
 	a.Catalog()
+	go a.WatchConfiguration(context.Background())
 
 	go func() {
-		a.registerHotkey()
+		a.registerHotkeys()
 		a.isVisible = true
 
 		if err := a.run(); err != nil {
@@ -52,6 +63,9 @@ func main() {
 		}
 
 		log.Println("Mainloop is done")
+		if err := a.engine.History().Flush(); err != nil {
+			log.Printf("[ERROR] Failed to write history: %v\n", err)
+		}
 		systray.Quit()
 	}()
 
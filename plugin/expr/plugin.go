@@ -6,13 +6,11 @@ import (
 	_ "embed"
 	"fmt"
 	"image"
-	"log"
 	"strconv"
 
 	"go-keyboard-launcher/api"
 
 	"github.com/hashicorp/go-hclog"
-	"golang.design/x/clipboard"
 )
 
 //go:embed icon.png
@@ -22,7 +20,19 @@ const (
 	ExpressionCategory = api.User + 1
 )
 
+const (
+	ActionCopyDecimal = "copy-decimal"
+	ActionCopyHex     = "copy-hex"
+)
+
+func init() {
+	api.Register("expr", func(name string) api.Plugin {
+		return &Plugin{name: name}
+	})
+}
+
 type Plugin struct {
+	name string
 	log  hclog.Logger
 	icon *image.Image
 }
@@ -39,30 +49,49 @@ func (p *Plugin) LoadConfig(f func(interface{}) error) {
 	// No configuration to load
 }
 
-func (p *Plugin) Catalog(context.Context) error {
+func (p *Plugin) Catalog(context.Context, api.CatalogCallback) error {
 	// Items are dynamic, nothing to do yet
 	return nil
 }
 
 func (p *Plugin) Name() string {
-	return "expr"
+	return p.name
 }
 
 func (p *Plugin) Icon() *image.Image {
 	return p.icon
 }
 
-func (p *Plugin) GetItems() ([]api.Item, error) {
-	return nil, nil
+func (p *Plugin) Execute(ctx context.Context, item api.Item, action string) error {
+	text, err := resultText(item, action)
+	if err != nil {
+		return err
+	}
+
+	return api.CopyToClipboard(text)
 }
 
-func (p *Plugin) Execute(item api.Item) {
-	if item.Category == ExpressionCategory {
-		clipboard.Write(clipboard.FmtText, []byte(item.Target))
+// resultText returns the text the action copies, the result as it is shown by default. Only integer results can be
+// copied as decimal or hex.
+func resultText(item api.Item, action string) (string, error) {
+	if item.Category != ExpressionCategory {
+		return "", fmt.Errorf("I don't know how to execute item %s", item.String())
+	}
 
-	} else {
-		log.Printf("I don't know how to execute item %s", item.String())
+	if action != ActionCopyDecimal && action != ActionCopyHex {
+		return item.Target, nil
 	}
+
+	value, ok := item.Data.(int64)
+	if !ok {
+		return "", fmt.Errorf("%s is not an integer", item.Target)
+	}
+
+	if action == ActionCopyHex {
+		return fmt.Sprintf("0x%s", strconv.FormatInt(value, 16)), nil
+	}
+
+	return fmt.Sprintf("%d", value), nil
 }
 
 func (p *Plugin) Suggest(ctx context.Context, input string, chain []api.Item, setSuggestions api.SuggestionCallback) {
@@ -76,22 +105,36 @@ func (p *Plugin) Suggest(ctx context.Context, input string, chain []api.Item, se
 
 	if result == float64(int64(result)) {
 		intValue := int64(result)
+		description := "Press Enter to copy the result, Ctrl+Enter to copy decimal, Alt+Enter to copy hex"
+		actions := []api.Action{
+			{ID: ActionCopyDecimal, Label: "Copy decimal"},
+			{ID: ActionCopyHex, Label: "Copy hex"},
+		}
 
 		setSuggestions([]api.Item{{
 			Label:       fmt.Sprintf("= %d", intValue),
-			Description: "Press Enter to copy the result",
+			Description: description,
 			Category:    ExpressionCategory,
 			Target:      fmt.Sprintf("%d", intValue),
+			Data:        intValue,
+			Actions:     actions,
+			HitHint:     api.HitTop,
 		}, {
 			Label:       fmt.Sprintf("= 0x%s", strconv.FormatInt(intValue, 16)),
-			Description: "Press Enter to copy the result",
+			Description: description,
 			Category:    ExpressionCategory,
-			Target:      fmt.Sprintf("%d", intValue),
+			Target:      fmt.Sprintf("0x%s", strconv.FormatInt(intValue, 16)),
+			Data:        intValue,
+			Actions:     actions,
+			HitHint:     api.HitTop,
 		}, {
 			Label:       fmt.Sprintf("= 0b%s", strconv.FormatInt(intValue, 2)),
-			Description: "Press Enter to copy the result",
+			Description: description,
 			Category:    ExpressionCategory,
-			Target:      fmt.Sprintf("%d", intValue),
+			Target:      fmt.Sprintf("0b%s", strconv.FormatInt(intValue, 2)),
+			Data:        intValue,
+			Actions:     actions,
+			HitHint:     api.HitTop,
 		}}, api.MatchAny)
 	} else {
 		setSuggestions([]api.Item{{
@@ -99,6 +142,7 @@ func (p *Plugin) Suggest(ctx context.Context, input string, chain []api.Item, se
 			Description: "Press Enter to copy the result",
 			Category:    ExpressionCategory,
 			Target:      fmt.Sprintf("%f", result),
+			HitHint:     api.HitTop,
 		}}, api.MatchAny)
 	}
 }
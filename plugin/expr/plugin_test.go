@@ -0,0 +1,65 @@
+package expr
+
+import (
+	"context"
+	"testing"
+
+	"go-keyboard-launcher/api"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func suggest(t *testing.T, input string) []api.Item {
+	t.Helper()
+
+	var items []api.Item
+	p := &Plugin{name: "expr"}
+	p.Suggest(context.Background(), input, nil, func(suggested []api.Item, _ api.Match) {
+		items = suggested
+	})
+
+	return items
+}
+
+func TestPlugin_SuggestInteger(t *testing.T) {
+	items := suggest(t, "0x10 + 1")
+	require.Len(t, items, 3)
+
+	// Enter copies the result in the base it is shown in
+	var targets []string
+	for _, each := range items {
+		text, err := resultText(each, api.DefaultAction)
+		require.NoError(t, err)
+		assert.Equal(t, each.Target, text)
+		targets = append(targets, text)
+	}
+	assert.Equal(t, []string{"17", "0x11", "0b10001"}, targets)
+
+	// The actions copy the result in their base whichever item they are run on
+	for _, each := range items {
+		text, err := resultText(each, ActionCopyDecimal)
+		require.NoError(t, err)
+		assert.Equal(t, "17", text)
+
+		text, err = resultText(each, ActionCopyHex)
+		require.NoError(t, err)
+		assert.Equal(t, "0x11", text)
+	}
+}
+
+func TestPlugin_SuggestFraction(t *testing.T) {
+	items := suggest(t, "1 / 4")
+	require.Len(t, items, 1)
+
+	text, err := resultText(items[0], api.DefaultAction)
+	require.NoError(t, err)
+	assert.Equal(t, "0.250000", text)
+
+	// A fraction has no hex value, the action fails instead of panicking
+	_, err = resultText(items[0], ActionCopyHex)
+	assert.EqualError(t, err, "0.250000 is not an integer")
+
+	_, err = resultText(api.Item{Label: "other", Category: api.User}, api.DefaultAction)
+	assert.Error(t, err)
+}
@@ -78,6 +78,11 @@ func (p *PagingIterator[T]) atEndOfPage() bool {
 	return p.i == len(p.items)-1
 }
 
+// AtEndOfPage returns true if the item last returned by Next was the last one of its page
+func (p *PagingIterator[T]) AtEndOfPage() bool {
+	return p.atEndOfPage()
+}
+
 func (p *PagingIterator[T]) hasNextPage() bool {
 	return len(p.nextLink) > 0
 }
@@ -6,6 +6,12 @@ type Owner struct {
 	Login string `json:"login"`
 }
 
+type PullBranch struct {
+	Label string `json:"label"`
+	Ref   string `json:"ref"`
+	Sha   string `json:"sha"`
+}
+
 type Pull struct {
 	Url               string `json:"url"`
 	HtmlUrl           string `json:"html_url"`
@@ -26,6 +32,9 @@ type Pull struct {
 	User   Owner     `json:"user"`
 	Body   string    `json:"body"`
 
+	Head PullBranch `json:"head"`
+	Base PullBranch `json:"base"`
+
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 	ClosedAt  time.Time `json:"closed_at"`
@@ -33,21 +42,22 @@ type Pull struct {
 }
 
 type Repository struct {
-	Id            int    `json:"id"`
-	Name          string `json:"name"`
-	FullName      string `json:"full_name"`
-	Owner         Owner  `json:"owner"`
-	Private       bool   `json:"private"`
-	HtmlUrl       string `json:"html_url"`
-	Description   string `json:"description"`
-	Url           string `json:"url"`
-	DefaultBranch string `json:"default_branch"`
-	Archived      bool   `json:"archived"`
-	Disabled      bool   `json:"disabled"`
-	Visibility    string `json:"visibility"`
-	PushedAt      string `json:"pushed_at"`
-	CreatedAt     string `json:"created_at"`
-	UpdatedAt     string `json:"updated_at"`
+	Id            int      `json:"id"`
+	Name          string   `json:"name"`
+	FullName      string   `json:"full_name"`
+	Owner         Owner    `json:"owner"`
+	Private       bool     `json:"private"`
+	HtmlUrl       string   `json:"html_url"`
+	Description   string   `json:"description"`
+	Topics        []string `json:"topics"`
+	Url           string   `json:"url"`
+	DefaultBranch string   `json:"default_branch"`
+	Archived      bool     `json:"archived"`
+	Disabled      bool     `json:"disabled"`
+	Visibility    string   `json:"visibility"`
+	PushedAt      string   `json:"pushed_at"`
+	CreatedAt     string   `json:"created_at"`
+	UpdatedAt     string   `json:"updated_at"`
 }
 
 type Object struct {
@@ -6,7 +6,7 @@ import (
 	_ "embed"
 	"fmt"
 	"image"
-	"log"
+	"sync"
 	"time"
 
 	"go-keyboard-launcher/api"
@@ -28,34 +28,63 @@ const (
 	KeywordConfigure uint8 = iota
 )
 
+const (
+	ActionCopyBranch = "copy-branch"
+	ActionCopyNumber = "copy-number"
+)
+
 type Config struct {
 	Token string
 }
 
+func init() {
+	api.Register("github", func(name string) api.Plugin {
+		return &Plugin{name: name}
+	})
+}
+
 type Plugin struct {
+	name string
 	log  hclog.Logger
 	icon *image.Image
 
-	config       Config
-	state        []string
-	repositories []api.Item
-	client       *github.GithubRestClient
+	config Config
+	state  []string
+
+	// The client of the configured token, guarded by mutex since the configuration may change while searching
+	mutex  sync.Mutex
+	client *github.GithubRestClient
 }
 
 func (p *Plugin) Name() string {
-	return "github"
+	return p.name
+}
+
+func (p *Plugin) NewConfig() interface{} {
+	return &Config{}
 }
 
 func (p *Plugin) LoadConfig(load func(interface{}) error) {
-	if err := load(&p.config); err != nil {
+	c := Config{}
+	if err := load(&c); err != nil {
 		fmt.Printf("Failed to load github config")
 	} else {
 		fmt.Printf("Config loaded")
 
-		p.client = &github.GithubRestClient{Token: p.config.Token}
+		p.mutex.Lock()
+		p.config = c
+		p.client = &github.GithubRestClient{Token: c.Token}
+		p.mutex.Unlock()
 	}
 }
 
+func (p *Plugin) currentClient() *github.GithubRestClient {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return p.client
+}
+
 func (p *Plugin) Initialize(log hclog.Logger) {
 	p.log = log
 
@@ -65,10 +94,10 @@ func (p *Plugin) Initialize(log hclog.Logger) {
 	}
 }
 
-func (p *Plugin) Catalog(ctx context.Context) error {
-	result := make([]api.Item, 0)
+func (p *Plugin) Catalog(ctx context.Context, callback api.CatalogCallback) error {
+	batch := make([]api.Item, 0)
 
-	it := p.client.ListRepositoriesForAuthenticatedUser(ctx)
+	it := p.currentClient().ListRepositoriesForAuthenticatedUser(ctx)
 
 	for {
 		found, repo, err := it.Next()
@@ -79,17 +108,28 @@ func (p *Plugin) Catalog(ctx context.Context) error {
 			break
 		}
 
-		result = append(result, api.Item{
+		batch = append(batch, api.Item{
+			ID:          repo.FullName,
 			Label:       repo.FullName,
 			Description: repo.Description,
+			Keywords:    repo.Topics,
 			Category:    api.Url,
 			Target:      repo.HtmlUrl,
 			ArgsHint:    api.Accepted,
 			Data:        repo.FullName,
 		})
+
+		// The repositories are delivered page by page, so they can be searched while the next page is fetched
+		if it.AtEndOfPage() {
+			callback(batch)
+			batch = make([]api.Item, 0)
+		}
+	}
+
+	if len(batch) > 0 {
+		callback(batch)
 	}
 
-	p.repositories = result
 	return nil
 }
 
@@ -97,12 +137,17 @@ func (p *Plugin) Icon() *image.Image {
 	return p.icon
 }
 
-func (p *Plugin) GetItems() ([]api.Item, error) {
-	return p.repositories, nil
-}
+func (p *Plugin) Execute(ctx context.Context, item api.Item, action string) error {
+	if pull, ok := item.Data.(github.Pull); ok {
+		switch action {
+		case ActionCopyBranch:
+			return api.CopyToClipboard(pull.Head.Ref)
+		case ActionCopyNumber:
+			return api.CopyToClipboard(fmt.Sprintf("#%d", pull.Number))
+		}
+	}
 
-func (p *Plugin) Execute(item api.Item) {
-	log.Printf("I don't know how to execute item %s", item.String())
+	return fmt.Errorf("I don't know how to execute item %s", item.String())
 }
 
 func (p *Plugin) Suggest(ctx context.Context, input string, chain []api.Item, setSuggestions api.SuggestionCallback) {
@@ -111,17 +156,20 @@ func (p *Plugin) Suggest(ctx context.Context, input string, chain []api.Item, se
 	} else if len(chain) == 1 {
 		setSuggestions([]api.Item{
 			{
+				ID:       "pulls",
 				Label:    fmt.Sprintf("Pull requests"),
 				Category: PullRequestCategory,
 				Target:   fmt.Sprintf("%s/pulls", chain[0].Target),
 				ArgsHint: api.Accepted,
 			},
 			{
+				ID:       "tags",
 				Label:    fmt.Sprintf("Tags"),
 				Category: TagsCategory,
 				ArgsHint: api.Required,
 			},
 			{
+				ID:       "branches",
 				Label:    fmt.Sprintf("Branches"),
 				Category: BranchesCategory,
 				ArgsHint: api.Required,
@@ -132,7 +180,7 @@ func (p *Plugin) Suggest(ctx context.Context, input string, chain []api.Item, se
 
 		switch chain[1].Category {
 		case TagsCategory:
-			it := p.client.ListMatchingRefs(ctx, github.ListMatchingRefsRequest{
+			it := p.currentClient().ListMatchingRefs(ctx, github.ListMatchingRefsRequest{
 				Repo: repoName,
 				Ref:  "tags/",
 			})
@@ -142,13 +190,14 @@ func (p *Plugin) Suggest(ctx context.Context, input string, chain []api.Item, se
 			for {
 				ok, item, err := it.Next()
 				if err != nil {
-					log.Printf("[Error] %s", err)
+					p.reportError(err, setSuggestions)
 					return
 				} else if !ok {
 					break
 				}
 
 				suggestions = append(suggestions, api.Item{
+					ID:       fmt.Sprintf("%s@%s", repoName, item.Ref),
 					Label:    item.Ref,
 					Category: api.Url,
 					Target:   item.Url,
@@ -159,7 +208,7 @@ func (p *Plugin) Suggest(ctx context.Context, input string, chain []api.Item, se
 			setSuggestions(suggestions, api.MatchFuzzy)
 
 		case PullRequestCategory:
-			it := p.client.ListPulls(ctx, github.ListPullsRequest{
+			it := p.currentClient().ListPulls(ctx, github.ListPullsRequest{
 				Repo:    repoName,
 				State:   github.Open,
 				PerPage: 30,
@@ -172,8 +221,7 @@ func (p *Plugin) Suggest(ctx context.Context, input string, chain []api.Item, se
 			for {
 				ok, item, err := it.Next()
 				if err != nil {
-					p.log.Debug("Error while retrieving data")
-					log.Printf("[Error] %s", err)
+					p.reportError(err, setSuggestions)
 					return
 				} else if !ok {
 					break
@@ -182,19 +230,32 @@ func (p *Plugin) Suggest(ctx context.Context, input string, chain []api.Item, se
 				delta := humanReadableTimeDelta(now.Sub(item.CreatedAt))
 
 				suggestions = append(suggestions, api.Item{
+					ID:          fmt.Sprintf("%s#%d", repoName, item.Number),
 					Label:       item.Title,
 					Description: fmt.Sprintf("#%d  opened %s  by %s", item.Number, delta, item.User.Login),
 					Category:    api.Url,
 					Target:      item.HtmlUrl,
 					ArgsHint:    api.Forbidden,
+					Data:        item,
+					Actions: []api.Action{
+						{ID: ActionCopyBranch, Label: "Copy branch name"},
+						{ID: ActionCopyNumber, Label: fmt.Sprintf("Copy #%d", item.Number)},
+					},
 				})
 			}
 
-			setSuggestions(suggestions, api.MatchFuzzy)
+			// Keep the order of the API, newest pull requests first
+			setSuggestions(suggestions, api.MatchOrdered)
 		}
 	}
 }
 
+// reportError shows an error that occurred while retrieving data as the only suggestion
+func (p *Plugin) reportError(err error, setSuggestions api.SuggestionCallback) {
+	p.log.Error("Error while retrieving data", "error", err)
+	setSuggestions([]api.Item{api.NewErrorItem("GitHub", err)}, api.MatchAny)
+}
+
 func humanReadableTimeDelta(sub time.Duration) string {
 
 	printUnit := func(n int, unit string) string {
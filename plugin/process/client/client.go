@@ -0,0 +1,305 @@
+// Package client runs an api.Plugin as an external plugin process, so plugins can be written in Go without being
+// compiled into the launcher. The main function of the plugin calls Run:
+//
+//	func main() {
+//		client.Run(&Plugin{})
+//	}
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"go-keyboard-launcher/api"
+	"go-keyboard-launcher/plugin/process/protocol"
+
+	"github.com/BurntSushi/toml"
+	"github.com/hashicorp/go-hclog"
+)
+
+// Run serves the plugin on stdin and stdout until the launcher closes stdin, the plugin should not write to stdout
+// itself
+func Run(plugin api.Plugin) {
+	if err := Serve(context.Background(), plugin, os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// Serve answers the requests the launcher sends on r until r is closed or the context is done. The requests are
+// handled concurrently, except initialize which is handled before any other request is read.
+func Serve(ctx context.Context, plugin api.Plugin, r io.Reader, w io.Writer) error {
+	s := server{
+		plugin:  plugin,
+		conn:    protocol.NewConn(r, w),
+		cancels: make(map[int64]context.CancelFunc),
+	}
+	defer s.wait.Wait()
+
+	// The requests that are still being handled are cancelled before waiting for them
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	received := make(chan protocol.Message)
+	failed := make(chan error, 1)
+
+	go func() {
+		for {
+			m, err := s.conn.Receive()
+			if err != nil {
+				failed <- err
+				return
+			}
+
+			select {
+			case received <- m:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-failed:
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		case m := <-received:
+			s.dispatch(ctx, m)
+		}
+	}
+}
+
+type server struct {
+	plugin api.Plugin
+	conn   *protocol.Conn
+	wait   sync.WaitGroup
+
+	mutex   sync.Mutex
+	cancels map[int64]context.CancelFunc // The requests that are being handled
+}
+
+func (s *server) dispatch(ctx context.Context, m protocol.Message) {
+	switch {
+	case m.Method == protocol.MethodCancel:
+		var params protocol.CancelParams
+		if json.Unmarshal(m.Params, &params) == nil {
+			s.cancel(params.ID)
+		}
+	case m.ID == nil:
+		// Unknown notifications are ignored
+	case m.Method == protocol.MethodInitialize:
+		s.reply(*m.ID, s.initialize(m.Params))
+	default:
+		id := *m.ID
+
+		ctx, cancel := context.WithCancel(ctx)
+		s.mutex.Lock()
+		s.cancels[id] = cancel
+		s.mutex.Unlock()
+
+		s.wait.Add(1)
+		go func() {
+			defer s.wait.Done()
+			defer s.cancel(id)
+
+			s.reply(id, s.handle(ctx, id, m))
+		}()
+	}
+}
+
+func (s *server) cancel(id int64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if cancel, found := s.cancels[id]; found {
+		cancel()
+		delete(s.cancels, id)
+	}
+}
+
+// result is the outcome of a request
+type result struct {
+	value interface{}
+	err   *protocol.Error
+}
+
+func failure(code int, format string, args ...interface{}) result {
+	return result{err: &protocol.Error{Code: code, Message: fmt.Sprintf(format, args...)}}
+}
+
+func (s *server) reply(id int64, r result) {
+	if err := s.conn.Reply(id, r.value, r.err); err != nil {
+		fmt.Fprintln(os.Stderr, "Could not reply:", err)
+	}
+}
+
+func (s *server) initialize(data json.RawMessage) result {
+	var params protocol.InitializeParams
+	if err := json.Unmarshal(data, &params); err != nil {
+		return failure(protocol.CodeInvalidParams, "invalid parameters: %s", err)
+	}
+
+	logger := hclog.NewInterceptLogger(&hclog.LoggerOptions{
+		Name:   params.Name,
+		Level:  hclog.Trace,
+		Output: io.Discard,
+	})
+	logger.RegisterSink(logSink{conn: s.conn})
+
+	s.plugin.Initialize(logger)
+	s.plugin.LoadConfig(func(i interface{}) error {
+		return decodeSettings(params.Settings, i)
+	})
+
+	var initialized protocol.InitializeResult
+	if icon := s.plugin.Icon(); icon != nil {
+		encoded, err := protocol.EncodeIcon(*icon)
+		if err != nil {
+			return failure(protocol.CodeInternalError, "%s", err)
+		}
+		initialized.Icon = encoded
+	}
+
+	return result{value: initialized}
+}
+
+// decodeSettings decodes the settings into the configuration struct of the plugin like the launcher decodes the
+// configuration of its built-in plugins, by encoding them as TOML again
+func decodeSettings(settings map[string]interface{}, i interface{}) error {
+	var buffer bytes.Buffer
+	if err := toml.NewEncoder(&buffer).Encode(normalize(settings)); err != nil {
+		return err
+	}
+
+	_, err := toml.Decode(buffer.String(), i)
+	return err
+}
+
+// normalize turns the whole numbers that JSON decodes as float64 back into integers, so they can be decoded into
+// integer fields
+func normalize(value interface{}) interface{} {
+	switch v := value.(type) {
+	case float64:
+		if v == float64(int64(v)) {
+			return int64(v)
+		}
+	case []interface{}:
+		for idx := range v {
+			v[idx] = normalize(v[idx])
+		}
+	case map[string]interface{}:
+		for key := range v {
+			v[key] = normalize(v[key])
+		}
+	}
+
+	return value
+}
+
+func (s *server) handle(ctx context.Context, id int64, m protocol.Message) (r result) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			r = failure(protocol.CodeInternalError, "plugin panicked: %v", recovered)
+		}
+	}()
+
+	switch m.Method {
+	case protocol.MethodCatalog:
+		err := s.plugin.Catalog(ctx, func(items []api.Item) {
+			s.sendItems(id, items, api.MatchFuzzy)
+		})
+		return s.outcome(ctx, err)
+
+	case protocol.MethodSuggest:
+		var params protocol.SuggestParams
+		if err := json.Unmarshal(m.Params, &params); err != nil {
+			return failure(protocol.CodeInvalidParams, "invalid parameters: %s", err)
+		}
+
+		chain, err := protocol.ToItems(params.Chain)
+		if err != nil {
+			return failure(protocol.CodeInvalidParams, "%s", err)
+		}
+
+		s.plugin.Suggest(ctx, params.Input, chain, func(items []api.Item, match api.Match) {
+			s.sendItems(id, items, match)
+		})
+		return s.outcome(ctx, nil)
+
+	case protocol.MethodExecute:
+		var params protocol.ExecuteParams
+		if err := json.Unmarshal(m.Params, &params); err != nil {
+			return failure(protocol.CodeInvalidParams, "invalid parameters: %s", err)
+		}
+
+		item, err := params.Item.ToItem()
+		if err != nil {
+			return failure(protocol.CodeInvalidParams, "%s", err)
+		}
+
+		return s.outcome(ctx, s.plugin.Execute(ctx, item, params.Action))
+
+	default:
+		return failure(protocol.CodeMethodNotFound, "unknown method %q", m.Method)
+	}
+}
+
+func (s *server) outcome(ctx context.Context, err error) result {
+	if ctx.Err() != nil {
+		return failure(protocol.CodeCancelled, "request cancelled")
+	} else if err != nil {
+		return failure(protocol.CodeInternalError, "%s", err)
+	}
+
+	return result{}
+}
+
+func (s *server) sendItems(id int64, items []api.Item, match api.Match) {
+	converted, err := protocol.FromItems(items)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not send items:", err)
+		return
+	}
+
+	err = s.conn.Notify(protocol.MethodItems, protocol.ItemsParams{
+		Request: id,
+		Items:   converted,
+		Match:   protocol.MatchName(match),
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not send items:", err)
+	}
+}
+
+// logSink sends the messages that the plugin logs to the launcher
+type logSink struct {
+	conn *protocol.Conn
+}
+
+func (l logSink) Accept(name string, level hclog.Level, msg string, args ...interface{}) {
+	// Errors and other values that cannot be encoded as JSON are sent as text
+	for idx, each := range args {
+		if err, ok := each.(error); ok {
+			args[idx] = err.Error()
+		} else if _, err := json.Marshal(each); err != nil {
+			args[idx] = fmt.Sprint(each)
+		}
+	}
+
+	_ = l.conn.Notify(protocol.MethodLog, protocol.LogParams{
+		Level:   level.String(),
+		Message: msg,
+		Args:    args,
+	})
+}
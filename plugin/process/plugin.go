@@ -0,0 +1,182 @@
+// Package process runs plugins as external processes that speak the protocol described in the README, so plugins can
+// be written in any language. Every instance is configured with the command that starts its process:
+//
+//	[plugin.my-python]
+//	type = "process"
+//	command = ["python3", "/home/me/launcher/my-python.py"]
+package process
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"image"
+	"sync"
+
+	"go-keyboard-launcher/api"
+	"go-keyboard-launcher/plugin/process/protocol"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+func init() {
+	api.RegisterType("process", func(name string) api.Plugin {
+		return &Plugin{name: name}
+	})
+}
+
+type config struct {
+	Command  []string               `toml:"command"`  // The executable and its arguments
+	Dir      string                 `toml:"dir"`      // The working directory of the process
+	Settings map[string]interface{} `toml:"settings"` // Passed to the process when it is initialized
+}
+
+type Plugin struct {
+	name string
+	log  hclog.Logger
+
+	mutex   sync.Mutex
+	config  config
+	process *process // Nil until the first request, and after the configuration changed
+
+	// The icon the process sent when it was initialized, it has its own mutex since starting the process takes a while
+	iconMutex sync.Mutex
+	icon      *image.Image
+}
+
+func (p *Plugin) Initialize(log hclog.Logger) {
+	p.log = log
+}
+
+func (p *Plugin) NewConfig() interface{} {
+	return &config{}
+}
+
+// LoadConfig stops the process, the next request starts it with the new configuration
+func (p *Plugin) LoadConfig(load func(interface{}) error) {
+	c := config{}
+	if err := load(&c); err != nil {
+		p.log.Error("Failed to load the configuration", "error", err)
+		return
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.config = c
+	if p.process != nil {
+		go p.process.stop()
+		p.process = nil
+	}
+}
+
+// running returns the process, it is started and initialized if it is not running. A process that exited is started
+// again.
+func (p *Plugin) running(ctx context.Context) (*process, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.process != nil && !p.process.exited() {
+		return p.process, nil
+	}
+
+	if len(p.config.Command) == 0 {
+		return nil, errors.New("no command configured")
+	}
+
+	proc, err := start(p.config, p.log)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := proc.call(ctx, protocol.MethodInitialize, protocol.InitializeParams{
+		Version:  protocol.Version,
+		Name:     p.name,
+		Settings: p.config.Settings,
+	}, nil)
+	if err != nil {
+		go proc.stop()
+		return nil, err
+	}
+
+	var initialized protocol.InitializeResult
+	if err := json.Unmarshal(result, &initialized); err != nil {
+		go proc.stop()
+		return nil, err
+	}
+
+	if len(initialized.Icon) > 0 {
+		icon, err := protocol.DecodeIcon(initialized.Icon)
+		if err != nil {
+			p.log.Warn("Ignoring the invalid icon of the plugin", "error", err)
+		} else {
+			p.iconMutex.Lock()
+			p.icon = icon
+			p.iconMutex.Unlock()
+		}
+	}
+
+	p.process = proc
+	return proc, nil
+}
+
+func (p *Plugin) Catalog(ctx context.Context, callback api.CatalogCallback) error {
+	proc, err := p.running(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = proc.call(ctx, protocol.MethodCatalog, nil, func(items []api.Item, match api.Match) {
+		callback(items)
+	})
+	return err
+}
+
+func (p *Plugin) Suggest(ctx context.Context, input string, chain []api.Item, callback api.SuggestionCallback) {
+	err := p.suggest(ctx, input, chain, callback)
+	if err != nil && ctx.Err() == nil {
+		p.log.Error("Failed to suggest", "error", err)
+		callback([]api.Item{api.NewErrorItem(p.name, err)}, api.MatchAny)
+	}
+}
+
+func (p *Plugin) suggest(ctx context.Context, input string, chain []api.Item, callback api.SuggestionCallback) error {
+	proc, err := p.running(ctx)
+	if err != nil {
+		return err
+	}
+
+	converted, err := protocol.FromItems(chain)
+	if err != nil {
+		return err
+	}
+
+	_, err = proc.call(ctx, protocol.MethodSuggest, protocol.SuggestParams{Input: input, Chain: converted}, callback)
+	return err
+}
+
+func (p *Plugin) Execute(ctx context.Context, item api.Item, action string) error {
+	proc, err := p.running(ctx)
+	if err != nil {
+		return err
+	}
+
+	converted, err := protocol.FromItem(item)
+	if err != nil {
+		return err
+	}
+
+	_, err = proc.call(ctx, protocol.MethodExecute, protocol.ExecuteParams{Item: converted, Action: action}, nil)
+	return err
+}
+
+func (p *Plugin) Icon() *image.Image {
+	p.iconMutex.Lock()
+	defer p.iconMutex.Unlock()
+
+	return p.icon
+}
+
+func (p *Plugin) Name() string {
+	return p.name
+}
@@ -0,0 +1,248 @@
+package process
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go-keyboard-launcher/api"
+	"go-keyboard-launcher/plugin/process/client"
+
+	"github.com/BurntSushi/toml"
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakePluginEnv makes the test binary run the fake plugin instead of the tests
+const fakePluginEnv = "GO_ANYWHERE_FAKE_PLUGIN"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(fakePluginEnv) == "1" {
+		client.Run(&fakePlugin{})
+		return
+	}
+
+	os.Exit(m.Run())
+}
+
+// fakePlugin is served by the test binary in a separate process
+type fakePlugin struct {
+	log    hclog.Logger
+	config struct {
+		Greeting string
+		Count    int
+	}
+
+	mutex     sync.Mutex
+	cancelled bool
+}
+
+func (p *fakePlugin) Initialize(log hclog.Logger) {
+	p.log = log
+}
+
+func (p *fakePlugin) LoadConfig(load func(interface{}) error) {
+	if err := load(&p.config); err != nil {
+		p.log.Error("Failed to load the configuration", "error", err)
+	}
+	p.log.Info("Configured", "greeting", p.config.Greeting, "error", errors.New("none"))
+}
+
+func (p *fakePlugin) Catalog(ctx context.Context, callback api.CatalogCallback) error {
+	callback([]api.Item{{ID: "alpha", Label: "Alpha", Category: api.Url, Icon: testIcon(), Data: map[string]interface{}{"n": 1.0}}})
+	callback([]api.Item{{Label: "Beta", Category: api.User, ArgsHint: api.Required, Actions: []api.Action{{ID: "copy", Label: "Copy"}}}})
+	return nil
+}
+
+func (p *fakePlugin) Suggest(ctx context.Context, input string, chain []api.Item, callback api.SuggestionCallback) {
+	switch input {
+	case "slow":
+		<-ctx.Done()
+
+		p.mutex.Lock()
+		p.cancelled = true
+		p.mutex.Unlock()
+	case "cancelled?":
+		p.mutex.Lock()
+		defer p.mutex.Unlock()
+
+		callback([]api.Item{{Label: fmt.Sprint(p.cancelled), Category: api.User}}, api.MatchAny)
+	case "crash":
+		os.Exit(3)
+	default:
+		var labels []string
+		for _, each := range chain {
+			labels = append(labels, each.Label)
+		}
+
+		for idx := 0; idx < p.config.Count; idx++ {
+			callback([]api.Item{{Label: p.config.Greeting + " " + input + " " + strings.Join(labels, ","), Category: api.User}}, api.MatchPrefix)
+		}
+	}
+}
+
+func (p *fakePlugin) Execute(ctx context.Context, item api.Item, action string) error {
+	if action != api.DefaultAction {
+		return fmt.Errorf("cannot %s %s", action, item.Label)
+	}
+	return nil
+}
+
+func (p *fakePlugin) Icon() *image.Image { return testIcon() }
+func (p *fakePlugin) Name() string       { return "fake" }
+
+func testIcon() *image.Image {
+	icon := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	icon.Set(1, 1, color.RGBA{R: 255, A: 255})
+
+	var result image.Image = icon
+	return &result
+}
+
+// safeBuffer collects the log of the plugin, which is written by the goroutine that reads the process
+type safeBuffer struct {
+	mutex  sync.Mutex
+	buffer bytes.Buffer
+}
+
+func (b *safeBuffer) Write(p []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buffer.Write(p)
+}
+
+func (b *safeBuffer) String() string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buffer.String()
+}
+
+func newTestPlugin(t *testing.T) (*Plugin, *safeBuffer) {
+	t.Setenv(fakePluginEnv, "1")
+
+	logs := &safeBuffer{}
+	p := &Plugin{name: "fake"}
+	p.Initialize(hclog.New(&hclog.LoggerOptions{Output: logs, Level: hclog.Trace}))
+
+	p.LoadConfig(func(i interface{}) error {
+		_, err := toml.Decode(fmt.Sprintf(`
+command = [%q]
+
+[settings]
+greeting = "Hello"
+count = 2
+`, os.Args[0]), i)
+		return err
+	})
+
+	t.Cleanup(func() {
+		p.mutex.Lock()
+		defer p.mutex.Unlock()
+
+		if p.process != nil {
+			p.process.stop()
+		}
+	})
+
+	return p, logs
+}
+
+func suggest(p *Plugin, input string, chain ...api.Item) ([]api.Item, api.Match) {
+	var items []api.Item
+	var match api.Match
+
+	p.Suggest(context.Background(), input, chain, func(suggested []api.Item, m api.Match) {
+		items = append(items, suggested...)
+		match = m
+	})
+
+	return items, match
+}
+
+func TestPlugin_Catalog(t *testing.T) {
+	p, logs := newTestPlugin(t)
+
+	var batches [][]api.Item
+	err := p.Catalog(context.Background(), func(items []api.Item) {
+		batches = append(batches, items)
+	})
+	require.NoError(t, err)
+	require.Len(t, batches, 2)
+
+	alpha := batches[0][0]
+	assert.Equal(t, "alpha", alpha.ID)
+	assert.Equal(t, api.Url, alpha.Category)
+	assert.Equal(t, map[string]interface{}{"n": 1.0}, alpha.Data)
+	require.NotNil(t, alpha.Icon)
+	assert.Equal(t, color.RGBA{R: 255, A: 255}, color.RGBAModel.Convert((*alpha.Icon).At(1, 1)))
+
+	beta := batches[1][0]
+	assert.Equal(t, api.Required, beta.ArgsHint)
+	assert.Equal(t, []api.Action{{ID: "copy", Label: "Copy"}}, beta.Actions)
+
+	assert.NotNil(t, p.Icon())
+	assert.Contains(t, logs.String(), "Configured: greeting=Hello error=none")
+}
+
+func TestPlugin_Suggest(t *testing.T) {
+	p, _ := newTestPlugin(t)
+
+	items, match := suggest(p, "world", api.Item{Label: "Beta", Category: api.User})
+	assert.Equal(t, []api.Item{{Label: "Hello world Beta", Category: api.User}, {Label: "Hello world Beta", Category: api.User}}, items)
+	assert.Equal(t, api.MatchPrefix, match)
+}
+
+func TestPlugin_SuggestCancelled(t *testing.T) {
+	p, _ := newTestPlugin(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	called := false
+	p.Suggest(ctx, "slow", nil, func([]api.Item, api.Match) { called = true })
+	assert.False(t, called)
+
+	// The plugin learns about the cancellation
+	assert.Eventually(t, func() bool {
+		items, _ := suggest(p, "cancelled?")
+		return len(items) == 1 && items[0].Label == "true"
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestPlugin_Execute(t *testing.T) {
+	p, _ := newTestPlugin(t)
+	item := api.Item{Label: "Beta", Category: api.User}
+
+	assert.NoError(t, p.Execute(context.Background(), item, api.DefaultAction))
+	assert.EqualError(t, p.Execute(context.Background(), item, "copy"), "cannot copy Beta")
+}
+
+func TestPlugin_Restart(t *testing.T) {
+	p, _ := newTestPlugin(t)
+
+	items, _ := suggest(p, "crash")
+	require.Len(t, items, 1)
+	assert.Equal(t, api.Error, items[0].Category)
+	assert.Contains(t, items[0].Label, "exit status 3")
+
+	// The next request starts the process again
+	items, _ = suggest(p, "again")
+	assert.Len(t, items, 2)
+}
+
+func TestPlugin_NoCommand(t *testing.T) {
+	p := &Plugin{name: "empty"}
+	p.Initialize(hclog.NewNullLogger())
+
+	err := p.Catalog(context.Background(), func([]api.Item) {})
+	assert.EqualError(t, err, "no command configured")
+}
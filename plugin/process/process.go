@@ -0,0 +1,252 @@
+package process
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"sync"
+	"time"
+
+	"go-keyboard-launcher/api"
+	"go-keyboard-launcher/plugin/process/protocol"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+// stopTimeout is how long a process may take to exit after its stdin was closed before it is killed
+const stopTimeout = 2 * time.Second
+
+// process is a running plugin process
+type process struct {
+	log   hclog.Logger
+	cmd   *exec.Cmd
+	conn  *protocol.Conn
+	stdin io.Closer
+
+	mutex   sync.Mutex
+	nextID  int64
+	pending map[int64]*call
+
+	logged chan struct{} // Closed when stderr was read to the end
+	done   chan struct{} // Closed when the process exited
+	err    error         // Why the process exited, set before done is closed
+}
+
+// call is a request that was not answered yet
+type call struct {
+	items    func([]api.Item, api.Match) // Receives the items the plugin sends for the request, may be nil
+	response chan protocol.Message
+}
+
+// start runs the command and reads its messages until it exits
+func start(c config, log hclog.Logger) (*process, error) {
+	cmd := exec.Command(c.Command[0], c.Command[1:]...)
+	cmd.Dir = c.Dir
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("could not start %s: %w", c.Command[0], err)
+	}
+
+	p := &process{
+		log:     log,
+		cmd:     cmd,
+		conn:    protocol.NewConn(stdout, stdin),
+		stdin:   stdin,
+		pending: make(map[int64]*call),
+		logged:  make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+
+	go p.logStderr(stderr)
+	go p.read()
+
+	return p, nil
+}
+
+// read dispatches the messages of the process until it closes stdout, then it waits for the process to exit
+func (p *process) read() {
+	var err error
+
+	for {
+		var m protocol.Message
+		m, err = p.conn.Receive()
+		if err != nil {
+			break
+		}
+
+		switch {
+		case m.IsResponse():
+			p.mutex.Lock()
+			c, found := p.pending[*m.ID]
+			delete(p.pending, *m.ID)
+			p.mutex.Unlock()
+
+			if found {
+				c.response <- m
+			}
+		case m.Method == protocol.MethodItems:
+			p.receiveItems(m.Params)
+		case m.Method == protocol.MethodLog:
+			p.receiveLog(m.Params)
+		default:
+			p.log.Warn("Ignoring unknown message", "method", m.Method)
+		}
+	}
+
+	// Wait closes stderr, so it has to be read to the end first
+	<-p.logged
+
+	if waitErr := p.cmd.Wait(); waitErr != nil {
+		err = waitErr
+	} else if errors.Is(err, io.EOF) {
+		err = errors.New("process exited")
+	}
+
+	p.err = fmt.Errorf("plugin process: %w", err)
+	close(p.done)
+}
+
+func (p *process) receiveItems(data json.RawMessage) {
+	var params protocol.ItemsParams
+	if err := json.Unmarshal(data, &params); err != nil {
+		p.log.Warn("Ignoring invalid items", "error", err)
+		return
+	}
+
+	p.mutex.Lock()
+	c, found := p.pending[params.Request]
+	p.mutex.Unlock()
+
+	// Items of cancelled requests are dropped
+	if !found || c.items == nil {
+		return
+	}
+
+	items, err := protocol.ToItems(params.Items)
+	if err != nil {
+		p.log.Warn("Ignoring invalid items", "error", err)
+		return
+	}
+
+	match, err := protocol.ParseMatch(params.Match)
+	if err != nil {
+		p.log.Warn("Ignoring invalid items", "error", err)
+		return
+	}
+
+	c.items(items, match)
+}
+
+func (p *process) receiveLog(data json.RawMessage) {
+	var params protocol.LogParams
+	if err := json.Unmarshal(data, &params); err != nil {
+		p.log.Warn("Ignoring invalid log message", "error", err)
+		return
+	}
+
+	level := hclog.LevelFromString(params.Level)
+	if level == hclog.NoLevel {
+		level = hclog.Info
+	}
+
+	p.log.Log(level, params.Message, params.Args...)
+}
+
+// logStderr logs what the process writes to stderr, e.g. a stack trace when it crashes
+func (p *process) logStderr(stderr io.Reader) {
+	defer close(p.logged)
+
+	scanner := bufio.NewScanner(stderr)
+	for scanner.Scan() {
+		p.log.Debug(scanner.Text())
+	}
+}
+
+// call sends the request and waits for its response, the items the plugin sends in the meantime are passed to the
+// callback. When the context is done the plugin is asked to cancel the request and its response is not waited for.
+func (p *process) call(ctx context.Context, method string, params interface{}, items func([]api.Item, api.Match)) (json.RawMessage, error) {
+	c := &call{
+		items:    items,
+		response: make(chan protocol.Message, 1),
+	}
+
+	p.mutex.Lock()
+	p.nextID++
+	id := p.nextID
+	p.pending[id] = c
+	p.mutex.Unlock()
+
+	forget := func() {
+		p.mutex.Lock()
+		delete(p.pending, id)
+		p.mutex.Unlock()
+	}
+
+	if err := p.conn.Request(id, method, params); err != nil {
+		forget()
+		return nil, p.failure(err)
+	}
+
+	select {
+	case m := <-c.response:
+		if m.Error != nil {
+			return nil, m.Error
+		}
+		return m.Result, nil
+	case <-ctx.Done():
+		forget()
+		_ = p.conn.Notify(protocol.MethodCancel, protocol.CancelParams{ID: id})
+		return nil, ctx.Err()
+	case <-p.done:
+		forget()
+		return nil, p.err
+	}
+}
+
+// failure returns why the process exited if writing to it failed because of that
+func (p *process) failure(err error) error {
+	select {
+	case <-p.done:
+		return p.err
+	default:
+		return err
+	}
+}
+
+func (p *process) exited() bool {
+	select {
+	case <-p.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// stop closes the stdin of the process, which asks it to exit, and kills it if it does not
+func (p *process) stop() {
+	_ = p.stdin.Close()
+
+	select {
+	case <-p.done:
+	case <-time.After(stopTimeout):
+		_ = p.cmd.Process.Kill()
+		<-p.done
+	}
+}
@@ -0,0 +1,248 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/png"
+
+	"go-keyboard-launcher/api"
+)
+
+// Item is an api.Item as it is sent over the connection. The enumerations are sent by name, an empty name is the
+// first value in their list.
+type Item struct {
+	ID          string      `json:"id,omitempty"`
+	Label       string      `json:"label"`
+	Description string      `json:"description,omitempty"`
+	Category    string      `json:"category,omitempty"` // user, keyword, file, url or error
+	Target      string      `json:"target,omitempty"`
+	Data        interface{} `json:"data,omitempty"`      // Passed back to the plugin as it was sent
+	Icon        string      `json:"icon,omitempty"`      // Base64 encoded PNG
+	ArgsHint    string      `json:"args_hint,omitempty"` // forbidden, accepted or required
+	Actions     []Action    `json:"actions,omitempty"`
+	Keywords    []string    `json:"keywords,omitempty"`
+	Score       float64     `json:"score,omitempty"`
+	HitHint     string      `json:"hit_hint,omitempty"` // normal, boost or top
+}
+
+// Action is an api.Action as it is sent over the connection
+type Action struct {
+	ID    string `json:"id"`
+	Label string `json:"label"`
+}
+
+var categories = []struct {
+	name  string
+	value api.ItemCategory
+}{
+	{"user", api.User},
+	{"keyword", api.Keyword},
+	{"file", api.File},
+	{"url", api.Url},
+	{"error", api.Error},
+}
+
+var argsHints = []string{"forbidden", "accepted", "required"}
+var hitHints = []string{"normal", "boost", "top"}
+
+// matches are the names of the api.Match values, fuzzy is first since it is the default
+var matches = []struct {
+	name  string
+	value api.Match
+}{
+	{"fuzzy", api.MatchFuzzy},
+	{"any", api.MatchAny},
+	{"prefix", api.MatchPrefix},
+	{"word", api.MatchWord},
+	{"ordered", api.MatchOrdered},
+}
+
+// FromItem converts the item for sending
+func FromItem(item api.Item) (Item, error) {
+	result := Item{
+		ID:          item.ID,
+		Label:       item.Label,
+		Description: item.Description,
+		Target:      item.Target,
+		Data:        item.Data,
+		Keywords:    item.Keywords,
+		Score:       item.Score,
+	}
+
+	for _, each := range item.Actions {
+		result.Actions = append(result.Actions, Action{ID: each.ID, Label: each.Label})
+	}
+
+	for _, each := range categories {
+		if each.value == item.Category {
+			result.Category = each.name
+		}
+	}
+	if len(result.Category) == 0 {
+		return Item{}, fmt.Errorf("item %q has unknown category %d", item.Label, item.Category)
+	}
+
+	if int(item.ArgsHint) >= len(argsHints) || int(item.HitHint) >= len(hitHints) {
+		return Item{}, fmt.Errorf("item %q has unknown hints", item.Label)
+	}
+	result.ArgsHint = argsHints[item.ArgsHint]
+	result.HitHint = hitHints[item.HitHint]
+
+	if item.Icon != nil {
+		icon, err := EncodeIcon(*item.Icon)
+		if err != nil {
+			return Item{}, err
+		}
+		result.Icon = icon
+	}
+
+	return result, nil
+}
+
+// FromItems converts the items for sending
+func FromItems(items []api.Item) ([]Item, error) {
+	result := make([]Item, 0, len(items))
+
+	for _, each := range items {
+		item, err := FromItem(each)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, item)
+	}
+
+	return result, nil
+}
+
+// ToItem converts a received item
+func (i Item) ToItem() (api.Item, error) {
+	result := api.Item{
+		ID:          i.ID,
+		Label:       i.Label,
+		Description: i.Description,
+		Target:      i.Target,
+		Data:        i.Data,
+		Keywords:    i.Keywords,
+		Score:       i.Score,
+	}
+
+	for _, each := range i.Actions {
+		result.Actions = append(result.Actions, api.Action{ID: each.ID, Label: each.Label})
+	}
+
+	found := len(i.Category) == 0
+	result.Category = api.User
+	for _, each := range categories {
+		if each.name == i.Category {
+			result.Category = each.value
+			found = true
+		}
+	}
+	if !found {
+		return api.Item{}, fmt.Errorf("item %q has unknown category %q", i.Label, i.Category)
+	}
+
+	argsHint, err := indexOf(argsHints, i.ArgsHint, "args hint")
+	if err != nil {
+		return api.Item{}, err
+	}
+	result.ArgsHint = api.ItemArgsHint(argsHint)
+
+	hitHint, err := indexOf(hitHints, i.HitHint, "hit hint")
+	if err != nil {
+		return api.Item{}, err
+	}
+	result.HitHint = api.ItemHitHint(hitHint)
+
+	if len(i.Icon) > 0 {
+		icon, err := DecodeIcon(i.Icon)
+		if err != nil {
+			return api.Item{}, fmt.Errorf("item %q has an invalid icon: %w", i.Label, err)
+		}
+		result.Icon = icon
+	}
+
+	return result, nil
+}
+
+// ToItems converts received items
+func ToItems(items []Item) ([]api.Item, error) {
+	result := make([]api.Item, 0, len(items))
+
+	for _, each := range items {
+		item, err := each.ToItem()
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, item)
+	}
+
+	return result, nil
+}
+
+func indexOf(names []string, name string, what string) (int, error) {
+	if len(name) == 0 {
+		return 0, nil
+	}
+
+	for idx, each := range names {
+		if each == name {
+			return idx, nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown %s %q", what, name)
+}
+
+// MatchName returns the name of the match for sending
+func MatchName(match api.Match) string {
+	for _, each := range matches {
+		if each.value == match {
+			return each.name
+		}
+	}
+
+	return ""
+}
+
+// ParseMatch returns the match with the given name, fuzzy if it is empty
+func ParseMatch(name string) (api.Match, error) {
+	if len(name) == 0 {
+		return api.MatchFuzzy, nil
+	}
+
+	for _, each := range matches {
+		if each.name == name {
+			return each.value, nil
+		}
+	}
+
+	return 0, fmt.Errorf("unknown match %q", name)
+}
+
+// EncodeIcon returns the icon as a base64 encoded PNG
+func EncodeIcon(icon image.Image) (string, error) {
+	var buffer bytes.Buffer
+	if err := png.Encode(&buffer, icon); err != nil {
+		return "", fmt.Errorf("could not encode the icon: %w", err)
+	}
+
+	return base64.StdEncoding.EncodeToString(buffer.Bytes()), nil
+}
+
+// DecodeIcon decodes a base64 encoded PNG
+func DecodeIcon(data string) (*image.Image, error) {
+	decoded, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return nil, err
+	}
+
+	icon, err := png.Decode(bytes.NewReader(decoded))
+	if err != nil {
+		return nil, err
+	}
+
+	return &icon, nil
+}
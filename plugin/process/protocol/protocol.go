@@ -0,0 +1,185 @@
+// Package protocol defines the messages that the launcher and an external plugin process exchange over the stdin and
+// stdout of the process. The protocol is described in the README of the process plugin.
+package protocol
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Version is the version of the protocol, it is passed to the plugin when it is initialized
+const Version = 1
+
+// The methods the launcher calls on the plugin
+const (
+	MethodInitialize = "initialize"
+	MethodCatalog    = "catalog"
+	MethodSuggest    = "suggest"
+	MethodExecute    = "execute"
+	MethodCancel     = "$/cancel" // A notification, the plugin should stop working on the request
+)
+
+// The notifications the plugin sends to the launcher
+const (
+	MethodItems = "items" // Items for a pending catalog or suggest request
+	MethodLog   = "log"
+)
+
+// The error codes of responses, as defined by JSON-RPC 2.0 and the Language Server Protocol
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+	CodeCancelled      = -32800
+)
+
+// Message is a JSON-RPC 2.0 request, response or notification. A request has an ID and a method, a response only an
+// ID and a notification only a method.
+type Message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      *int64          `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+// IsResponse returns true if the message answers a request
+func (m Message) IsResponse() bool {
+	return m.ID != nil && len(m.Method) == 0
+}
+
+// Error is the error of a failed request
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// InitializeParams is sent once, before any other request
+type InitializeParams struct {
+	Version  int                    `json:"version"`
+	Name     string                 `json:"name"`               // The name of the plugin in the configuration
+	Settings map[string]interface{} `json:"settings,omitempty"` // The [plugin.<name>.settings] table
+}
+
+// InitializeResult describes the plugin
+type InitializeResult struct {
+	Icon string `json:"icon,omitempty"` // Base64 encoded PNG
+}
+
+// SuggestParams asks for the items that match the input, chain holds the items that were selected before
+type SuggestParams struct {
+	Input string `json:"input"`
+	Chain []Item `json:"chain"`
+}
+
+// ExecuteParams asks to execute the action of the item, the action is empty for the default action
+type ExecuteParams struct {
+	Item   Item   `json:"item"`
+	Action string `json:"action,omitempty"`
+}
+
+// CancelParams identifies the request to cancel
+type CancelParams struct {
+	ID int64 `json:"id"`
+}
+
+// ItemsParams delivers items for the catalog or suggest request with the given ID, a request can receive any number
+// of them before it is answered
+type ItemsParams struct {
+	Request int64  `json:"request"`
+	Items   []Item `json:"items"`
+	Match   string `json:"match,omitempty"` // How the launcher matches suggestions against the input, see Match
+}
+
+// LogParams is a message for the log of the launcher. Args are alternating keys and values.
+type LogParams struct {
+	Level   string        `json:"level"` // trace, debug, info, warn or error
+	Message string        `json:"message"`
+	Args    []interface{} `json:"args,omitempty"`
+}
+
+// Conn reads and writes messages, one JSON value per line. Messages can be sent from any goroutine.
+type Conn struct {
+	mutex   sync.Mutex
+	encoder *json.Encoder
+	decoder *json.Decoder
+}
+
+func NewConn(r io.Reader, w io.Writer) *Conn {
+	return &Conn{
+		encoder: json.NewEncoder(w),
+		decoder: json.NewDecoder(r),
+	}
+}
+
+// Receive reads the next message, it returns io.EOF when the other side closed the connection
+func (c *Conn) Receive() (Message, error) {
+	var m Message
+	err := c.decoder.Decode(&m)
+	return m, err
+}
+
+func (c *Conn) send(m Message) error {
+	m.JSONRPC = "2.0"
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.encoder.Encode(m)
+}
+
+// Request sends a request, the response is returned by Receive
+func (c *Conn) Request(id int64, method string, params interface{}) error {
+	data, err := marshalParams(params)
+	if err != nil {
+		return err
+	}
+
+	return c.send(Message{ID: &id, Method: method, Params: data})
+}
+
+// Notify sends a notification, which is not answered
+func (c *Conn) Notify(method string, params interface{}) error {
+	data, err := marshalParams(params)
+	if err != nil {
+		return err
+	}
+
+	return c.send(Message{Method: method, Params: data})
+}
+
+// Reply answers the request with the result, or with the error if it is not nil
+func (c *Conn) Reply(id int64, result interface{}, failure *Error) error {
+	if failure != nil {
+		return c.send(Message{ID: &id, Error: failure})
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("could not encode the result: %w", err)
+	}
+
+	return c.send(Message{ID: &id, Result: data})
+}
+
+func marshalParams(params interface{}) (json.RawMessage, error) {
+	if params == nil {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(params)
+	if err != nil {
+		return nil, fmt.Errorf("could not encode the parameters: %w", err)
+	}
+
+	return data, nil
+}
@@ -0,0 +1,101 @@
+package scripts
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"go-keyboard-launcher/api"
+)
+
+// headerLines is how many lines at the start of a script are searched for metadata
+const headerLines = 30
+
+// commentPrefixes start the comment lines of the common script languages
+var commentPrefixes = []string{"#", "//", "--", "::", ";", "rem ", "REM "}
+
+// Output is what happens with the standard output of a script
+type Output string
+
+const (
+	OutputNone      Output = "none"      // The output is discarded
+	OutputClipboard Output = "clipboard" // The output is copied to the clipboard
+	OutputItems     Output = "items"     // Every line of the output is an item
+)
+
+// header is the metadata in the comments at the start of a script, e.g.
+//
+//	#!/bin/sh
+//	# @title Deploy to staging
+//	# @argument required
+type header struct {
+	Title       string
+	Description string
+	Argument    api.ItemArgsHint
+	Output      Output
+	Icon        string // The file of the icon, relative to the directory of the scripts
+}
+
+// parseHeader reads the metadata of the script, the keys that are not set keep their defaults
+func parseHeader(r io.Reader) (header, error) {
+	h := header{Argument: api.Forbidden, Output: OutputNone}
+
+	scanner := bufio.NewScanner(r)
+	for idx := 0; idx < headerLines && scanner.Scan(); idx++ {
+		key, value, found := headerField(scanner.Text())
+		if !found {
+			continue
+		}
+
+		switch key {
+		case "title":
+			h.Title = value
+		case "description":
+			h.Description = value
+		case "icon":
+			h.Icon = value
+		case "argument":
+			switch value {
+			case "none":
+				h.Argument = api.Forbidden
+			case "optional":
+				h.Argument = api.Accepted
+			case "required":
+				h.Argument = api.Required
+			default:
+				return h, fmt.Errorf("line %d: argument must be none, optional or required, not %q", idx+1, value)
+			}
+		case "output":
+			switch Output(value) {
+			case OutputNone, OutputClipboard, OutputItems:
+				h.Output = Output(value)
+			default:
+				return h, fmt.Errorf("line %d: output must be none, clipboard or items, not %q", idx+1, value)
+			}
+		}
+	}
+
+	return h, scanner.Err()
+}
+
+// headerField returns the key and value of a comment line like `# @title Deploy`
+func headerField(line string) (string, string, bool) {
+	line = strings.TrimSpace(line)
+
+	for _, prefix := range commentPrefixes {
+		if !strings.HasPrefix(line, prefix) {
+			continue
+		}
+
+		field := strings.TrimSpace(strings.TrimPrefix(line, prefix))
+		if !strings.HasPrefix(field, "@") {
+			return "", "", false
+		}
+
+		key, value, _ := strings.Cut(field[1:], " ")
+		return strings.ToLower(key), strings.TrimSpace(value), true
+	}
+
+	return "", "", false
+}
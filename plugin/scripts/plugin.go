@@ -0,0 +1,416 @@
+// Package scripts turns the executable scripts in a directory into items. The metadata of a script is read from the
+// comments at its start, see the README.
+package scripts
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/png"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"go-keyboard-launcher/api"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+const (
+	OutputCategory = api.User + 1 // A line of the output of a script
+)
+
+// maxOutputItems is the number of lines of the output that are shown as items
+const maxOutputItems = 200
+
+func init() {
+	api.Register("scripts", func(name string) api.Plugin {
+		return &Plugin{name: name}
+	})
+}
+
+type config struct {
+	Dir string `toml:"dir"` // The directory of the scripts, ~/.go-anywhere/scripts by default, ~/ is the home directory
+}
+
+type Plugin struct {
+	name string
+	log  hclog.Logger
+
+	mutex   sync.Mutex
+	config  config
+	scripts map[string]script // The scripts of the last catalog by file name
+	listing *listing          // The output of the script that was pushed last, if it lists items
+}
+
+// listing is a run of a script that lists items without an argument. It runs once when the script is pushed, the
+// input filters the items it listed.
+type listing struct {
+	file   string
+	cancel context.CancelFunc
+	done   chan struct{} // Closed when the script exited
+	items  []api.Item
+	err    error
+}
+
+// script is an executable file in the directory of the scripts
+type script struct {
+	file   string
+	header header
+}
+
+// invocation is the Data of a suggestion that runs a script with the argument
+type invocation struct {
+	file     string
+	argument string
+}
+
+func (p *Plugin) Initialize(log hclog.Logger) {
+	p.log = log
+}
+
+func (p *Plugin) NewConfig() interface{} {
+	return &config{}
+}
+
+func (p *Plugin) LoadConfig(load func(interface{}) error) {
+	c := config{}
+	if err := load(&c); err != nil {
+		p.log.Error("Failed to load the configuration", "error", err)
+		return
+	}
+
+	p.mutex.Lock()
+	p.config = c
+	p.mutex.Unlock()
+}
+
+func (p *Plugin) dir() string {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return p.config.Dir
+	}
+
+	if len(p.config.Dir) == 0 {
+		return filepath.Join(home, ".go-anywhere", "scripts")
+	} else if strings.HasPrefix(p.config.Dir, "~/") {
+		return filepath.Join(home, p.config.Dir[2:])
+	}
+
+	return p.config.Dir
+}
+
+func (p *Plugin) Catalog(ctx context.Context, callback api.CatalogCallback) error {
+	dir := p.dir()
+
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	scripts := make(map[string]script)
+	var items []api.Item
+
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || info.IsDir() || !isScript(info) {
+			continue
+		}
+
+		s, err := readScript(dir, entry.Name())
+		if err != nil {
+			p.log.Warn("Ignoring script", "file", entry.Name(), "error", err)
+			continue
+		}
+
+		scripts[s.file] = s
+		items = append(items, p.item(dir, s))
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].Label < items[j].Label
+	})
+
+	p.mutex.Lock()
+	p.scripts = scripts
+	p.mutex.Unlock()
+
+	callback(items)
+	return nil
+}
+
+func readScript(dir string, file string) (script, error) {
+	f, err := os.Open(filepath.Join(dir, file))
+	if err != nil {
+		return script{}, err
+	}
+	defer f.Close()
+
+	h, err := parseHeader(f)
+	if err != nil {
+		return script{}, err
+	}
+
+	if len(h.Title) == 0 {
+		h.Title = strings.TrimSuffix(file, filepath.Ext(file))
+	}
+
+	return script{file: file, header: h}, nil
+}
+
+func (p *Plugin) item(dir string, s script) api.Item {
+	argsHint := s.header.Argument
+	if s.header.Output == OutputItems {
+		// The output is listed after the script was selected
+		argsHint = api.Required
+	}
+
+	item := api.Item{
+		ID:          s.file,
+		Label:       s.header.Title,
+		Description: s.header.Description,
+		Category:    api.User,
+		Target:      filepath.Join(dir, s.file),
+		Data:        s.file,
+		ArgsHint:    argsHint,
+	}
+
+	if len(s.header.Icon) > 0 {
+		icon, err := loadIcon(filepath.Join(dir, s.header.Icon))
+		if err != nil {
+			p.log.Warn("Failed to load the icon of script", "file", s.file, "error", err)
+		} else {
+			item.Icon = icon
+		}
+	}
+
+	return item
+}
+
+func loadIcon(file string) (*image.Image, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	decoded, _, err := image.Decode(f)
+	if err != nil {
+		return nil, err
+	}
+
+	return &decoded, nil
+}
+
+// script returns the script with the file name, the catalog may have been loaded from the cache so it is read again
+// if it is not known
+func (p *Plugin) script(file string) (script, error) {
+	p.mutex.Lock()
+	s, found := p.scripts[file]
+	p.mutex.Unlock()
+
+	if found {
+		return s, nil
+	}
+
+	return readScript(p.dir(), file)
+}
+
+// Suggest lists the output of a script with the items output, or the item that runs the script with the input as
+// its argument
+func (p *Plugin) Suggest(ctx context.Context, input string, chain []api.Item, callback api.SuggestionCallback) {
+	if len(chain) == 0 {
+		return
+	}
+
+	file, ok := chain[len(chain)-1].Data.(string)
+	if !ok {
+		return
+	}
+
+	s, err := p.script(file)
+	if err != nil {
+		callback([]api.Item{api.NewErrorItem(p.name, err)}, api.MatchAny)
+		return
+	}
+
+	argument := strings.TrimSpace(input)
+	if s.header.Argument == api.Required && len(argument) == 0 {
+		return
+	}
+
+	if s.header.Output != OutputItems {
+		callback([]api.Item{{
+			Label:       strings.TrimSpace(s.header.Title + " " + argument),
+			Description: s.header.Description,
+			Category:    api.User,
+			Data:        invocation{file: file, argument: argument},
+		}}, api.MatchAny)
+		return
+	}
+
+	// A script without an argument lists its output once and it is filtered by the input
+	if s.header.Argument == api.Forbidden {
+		items, err := p.list(ctx, file, len(input) == 0)
+		if err != nil {
+			if ctx.Err() == nil {
+				callback([]api.Item{api.NewErrorItem(p.name, err)}, api.MatchAny)
+			}
+			return
+		}
+
+		callback(items, api.MatchFuzzy)
+		return
+	}
+
+	output, err := p.run(ctx, file, argument)
+	if err != nil {
+		if ctx.Err() == nil {
+			callback([]api.Item{api.NewErrorItem(p.name, err)}, api.MatchAny)
+		}
+		return
+	}
+
+	callback(outputItems(output), api.MatchAny)
+}
+
+// list returns the items the script lists without an argument. The script runs again when it was pushed, which is
+// when the input is empty, otherwise the items of the last run are returned, waiting for it if it is still running.
+func (p *Plugin) list(ctx context.Context, file string, pushed bool) ([]api.Item, error) {
+	p.mutex.Lock()
+	l := p.listing
+	if pushed || l == nil || l.file != file {
+		if l != nil {
+			l.cancel()
+		}
+
+		// The run outlives the search that started it, the next keystrokes wait for it
+		runCtx, cancel := context.WithCancel(context.Background())
+		l = &listing{file: file, cancel: cancel, done: make(chan struct{})}
+		p.listing = l
+
+		go func() {
+			defer close(l.done)
+
+			output, err := p.run(runCtx, file, "")
+			l.items, l.err = outputItems(output), err
+		}()
+	}
+	p.mutex.Unlock()
+
+	select {
+	case <-l.done:
+		return l.items, l.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// outputItems returns an item for every line of the output, a tab separates the label from the description
+func outputItems(output []byte) []api.Item {
+	var items []api.Item
+
+	for _, line := range strings.Split(string(output), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if len(strings.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		label, description, _ := strings.Cut(line, "\t")
+		items = append(items, api.Item{
+			Label:       label,
+			Description: description,
+			Category:    OutputCategory,
+			Target:      label,
+		})
+
+		if len(items) == maxOutputItems {
+			break
+		}
+	}
+
+	return items
+}
+
+// Execute runs the script, or copies a line of its output
+func (p *Plugin) Execute(ctx context.Context, item api.Item, action string) error {
+	if item.Category == OutputCategory {
+		return api.CopyToClipboard(item.Target)
+	}
+
+	var file, argument string
+	switch data := item.Data.(type) {
+	case string:
+		file = data
+	case invocation:
+		file, argument = data.file, data.argument
+	default:
+		return fmt.Errorf("I don't know how to execute item %s", item.String())
+	}
+
+	s, err := p.script(file)
+	if err != nil {
+		return err
+	}
+
+	output, err := p.run(ctx, file, argument)
+	if err != nil {
+		return err
+	}
+
+	if s.header.Output == OutputClipboard {
+		return api.CopyToClipboard(strings.TrimRight(string(output), "\r\n"))
+	}
+
+	return nil
+}
+
+// run runs the script with the argument, if it is not empty, and returns its output. The error of a failing script
+// includes the last line it wrote to stderr.
+func (p *Plugin) run(ctx context.Context, file string, argument string) ([]byte, error) {
+	dir := p.dir()
+
+	var args []string
+	if len(argument) > 0 {
+		args = append(args, argument)
+	}
+
+	cmd := command(ctx, filepath.Join(dir, file), args)
+	cmd.Dir = dir
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		lines := strings.Split(strings.TrimSpace(stderr.String()), "\n")
+		if last := strings.TrimSpace(lines[len(lines)-1]); len(last) > 0 {
+			return nil, fmt.Errorf("%s: %s", file, last)
+		}
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+
+	if stderr.Len() > 0 {
+		p.log.Debug("Script wrote to stderr", "file", file, "stderr", stderr.String())
+	}
+
+	return stdout.Bytes(), nil
+}
+
+func (p *Plugin) Icon() *image.Image {
+	return nil
+}
+
+func (p *Plugin) Name() string {
+	return p.name
+}
@@ -0,0 +1,178 @@
+package scripts
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"go-keyboard-launcher/api"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseHeader(t *testing.T) {
+	h, err := parseHeader(strings.NewReader(`#!/bin/sh
+# @title Deploy to staging
+# @description Deploys the current branch
+# @argument optional
+# @output clipboard
+# @icon deploy.png
+# @unknown is ignored
+echo "# @title is not a header"
+`))
+	require.NoError(t, err)
+	assert.Equal(t, header{
+		Title:       "Deploy to staging",
+		Description: "Deploys the current branch",
+		Argument:    api.Accepted,
+		Output:      OutputClipboard,
+		Icon:        "deploy.png",
+	}, h)
+
+	h, err = parseHeader(strings.NewReader("@echo off\r\nREM @title Batch\r\n:: @argument required\r\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "Batch", h.Title)
+	assert.Equal(t, api.Required, h.Argument)
+	assert.Equal(t, OutputNone, h.Output)
+
+	_, err = parseHeader(strings.NewReader("# @output screen\n"))
+	assert.EqualError(t, err, `line 1: output must be none, clipboard or items, not "screen"`)
+}
+
+func newTestPlugin(t *testing.T, scripts map[string]string) *Plugin {
+	if runtime.GOOS == "windows" {
+		t.Skip("the test scripts are shell scripts")
+	}
+
+	dir := t.TempDir()
+	for file, content := range scripts {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, file), []byte(content), 0755))
+	}
+
+	p := &Plugin{name: "scripts", config: config{Dir: dir}}
+	p.Initialize(hclog.NewNullLogger())
+
+	return p
+}
+
+func catalog(t *testing.T, p *Plugin) []api.Item {
+	var items []api.Item
+	require.NoError(t, p.Catalog(context.Background(), func(batch []api.Item) {
+		items = append(items, batch...)
+	}))
+
+	return items
+}
+
+func suggest(p *Plugin, input string, chain ...api.Item) ([]api.Item, api.Match) {
+	var items []api.Item
+	var match api.Match
+
+	p.Suggest(context.Background(), input, chain, func(suggested []api.Item, m api.Match) {
+		items = suggested
+		match = m
+	})
+
+	return items, match
+}
+
+func TestPlugin_Catalog(t *testing.T) {
+	p := newTestPlugin(t, map[string]string{
+		"deploy.sh": "#!/bin/sh\n# @title Deploy\n# @argument required\n",
+		"list.sh":   "#!/bin/sh\n# @output items\n",
+		"plain.sh":  "#!/bin/sh\n",
+	})
+	require.NoError(t, os.WriteFile(filepath.Join(p.config.Dir, "notes.txt"), []byte("# @title Notes"), 0644))
+
+	items := catalog(t, p)
+	require.Len(t, items, 3)
+
+	assert.Equal(t, "Deploy", items[0].Label)
+	assert.Equal(t, api.Required, items[0].ArgsHint)
+	assert.Equal(t, "list", items[1].Label)
+	assert.Equal(t, api.Required, items[1].ArgsHint, "the output is listed after selecting the script")
+	assert.Equal(t, "plain", items[2].Label)
+	assert.Equal(t, api.Forbidden, items[2].ArgsHint)
+}
+
+func TestPlugin_ExecuteWithArgument(t *testing.T) {
+	p := newTestPlugin(t, map[string]string{
+		"touch.sh": "#!/bin/sh\n# @argument required\ntouch \"$1\"\n",
+		"fail.sh":  "#!/bin/sh\necho 'something went wrong' >&2\nexit 1\n",
+	})
+	items := catalog(t, p)
+
+	suggestions, _ := suggest(p, "", items[1])
+	assert.Empty(t, suggestions, "the argument is required")
+
+	suggestions, _ = suggest(p, " created ", items[1])
+	require.Len(t, suggestions, 1)
+	assert.Equal(t, "touch created", suggestions[0].Label)
+
+	require.NoError(t, p.Execute(context.Background(), suggestions[0], api.DefaultAction))
+	assert.FileExists(t, filepath.Join(p.config.Dir, "created"))
+
+	err := p.Execute(context.Background(), items[0], api.DefaultAction)
+	assert.EqualError(t, err, "fail.sh: something went wrong")
+}
+
+func TestPlugin_OutputItems(t *testing.T) {
+	p := newTestPlugin(t, map[string]string{
+		"branches.sh": "#!/bin/sh\n# @output items\nprintf 'main\\tThe default branch\\nfeature\\n'\n",
+		"search.sh":   "#!/bin/sh\n# @output items\n# @argument optional\necho \"result for $1\"\n",
+	})
+	items := catalog(t, p)
+
+	// Without an argument the output is filtered by the input
+	suggestions, match := suggest(p, "ma", items[0])
+	assert.Equal(t, api.MatchFuzzy, match)
+	require.Len(t, suggestions, 2)
+	assert.Equal(t, "main", suggestions[0].Label)
+	assert.Equal(t, "The default branch", suggestions[0].Description)
+	assert.EqualValues(t, OutputCategory, suggestions[0].Category)
+
+	// With an argument the script decides
+	suggestions, match = suggest(p, "cats", items[1])
+	assert.Equal(t, api.MatchAny, match)
+	require.Len(t, suggestions, 1)
+	assert.Equal(t, "result for cats", suggestions[0].Label)
+}
+
+func TestPlugin_OutputItemsOncePerPush(t *testing.T) {
+	p := newTestPlugin(t, map[string]string{
+		"branches.sh": "#!/bin/sh\n# @output items\necho run >> runs\nprintf 'main\\nfeature\\n'\n",
+	})
+	items := catalog(t, p)
+
+	runs := func() int {
+		data, err := os.ReadFile(filepath.Join(p.config.Dir, "runs"))
+		require.NoError(t, err)
+		return strings.Count(string(data), "run")
+	}
+
+	// Pushing the script runs it, typing filters its output without running it again
+	for _, input := range []string{"", "m", "ma", "mai"} {
+		suggestions, _ := suggest(p, input, items[0])
+		assert.Len(t, suggestions, 2, input)
+	}
+	assert.Equal(t, 1, runs())
+
+	// Pushing it again lists the current output
+	suggest(p, "", items[0])
+	assert.Equal(t, 2, runs())
+}
+
+func TestPlugin_CachedCatalog(t *testing.T) {
+	p := newTestPlugin(t, map[string]string{
+		"touch.sh": "#!/bin/sh\ntouch cached\n",
+	})
+
+	// An item of a cached catalog is executed before the plugin built its catalog
+	require.NoError(t, p.Execute(context.Background(), api.Item{Label: "touch", Data: "touch.sh"}, api.DefaultAction))
+	assert.FileExists(t, filepath.Join(p.config.Dir, "cached"))
+}
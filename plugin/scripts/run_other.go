@@ -0,0 +1,19 @@
+//go:build !windows
+
+package scripts
+
+import (
+	"context"
+	"io/fs"
+	"os/exec"
+)
+
+// isScript returns true if the file is executable
+func isScript(info fs.FileInfo) bool {
+	return info.Mode().Perm()&0111 != 0
+}
+
+// command runs the script directly, its shebang line selects the interpreter
+func command(ctx context.Context, file string, args []string) *exec.Cmd {
+	return exec.CommandContext(ctx, file, args...)
+}
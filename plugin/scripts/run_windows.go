@@ -0,0 +1,29 @@
+package scripts
+
+import (
+	"context"
+	"io/fs"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// isScript tells scripts by their extension, since Windows has no executable permission
+func isScript(info fs.FileInfo) bool {
+	switch strings.ToLower(filepath.Ext(info.Name())) {
+	case ".bat", ".cmd", ".exe", ".ps1":
+		return true
+	default:
+		return false
+	}
+}
+
+// command runs PowerShell scripts with PowerShell, the other scripts can be run directly
+func command(ctx context.Context, file string, args []string) *exec.Cmd {
+	if strings.EqualFold(filepath.Ext(file), ".ps1") {
+		return exec.CommandContext(ctx, "powershell.exe",
+			append([]string{"-NoProfile", "-NonInteractive", "-ExecutionPolicy", "Bypass", "-File", file}, args...)...)
+	}
+
+	return exec.CommandContext(ctx, file, args...)
+}
@@ -2,11 +2,14 @@ package startmenu
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"image"
 	"io/fs"
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"go-keyboard-launcher/api"
 
@@ -14,32 +17,33 @@ import (
 )
 
 type Plugin struct {
-	icon  *image.Image
-	items []api.Item
+	name string
+	icon *image.Image
 }
 
 func (p *Plugin) LoadConfig(f func(interface{}) error) {
 	// No configuration to load
 }
 
-func (p *Plugin) Catalog(context.Context) error {
-	directory := filepath.Join(os.Getenv("ProgramData"), "Microsoft", "Windows", "Start Menu", "Programs")
-	err := collectApplicationsFrom(directory, &p.items)
-	if err != nil {
-		return err
-	}
+func (p *Plugin) Catalog(ctx context.Context, callback api.CatalogCallback) error {
+	// The applications of every start menu are delivered as a batch
+	for _, root := range []string{os.Getenv("ProgramData"), os.Getenv("AppData")} {
+		var items []api.Item
+
+		directory := filepath.Join(root, "Microsoft", "Windows", "Start Menu", "Programs")
+		err := collectApplicationsFrom(directory, &items)
+		if err != nil {
+			return err
+		}
 
-	directory = filepath.Join(os.Getenv("AppData"), "Microsoft", "Windows", "Start Menu", "Programs")
-	err = collectApplicationsFrom(directory, &p.items)
-	if err != nil {
-		return err
+		callback(items)
 	}
 
 	return nil
 }
 
 func (p *Plugin) Name() string {
-	return "win32-apps"
+	return p.name
 }
 
 func (p *Plugin) Initialize(log hclog.Logger) {
@@ -54,16 +58,21 @@ func (p *Plugin) Suggest(ctx context.Context, input string, chain []api.Item, ca
 	return
 }
 
-func (p *Plugin) Execute(item api.Item) {
-
-}
-
-func (p *Plugin) GetItems() ([]api.Item, error) {
-	return p.items, nil
+func (p *Plugin) Execute(ctx context.Context, item api.Item, action string) error {
+	// Items are files, which are executed by the launcher
+	return fmt.Errorf("I don't know how to execute item %s", item.String())
 }
 
 func collectApplicationsFrom(directory string, result *[]api.Item) error {
 	return filepath.Walk(directory, func(path string, info fs.FileInfo, err error) error {
+		if err != nil {
+			if path == directory && errors.Is(err, fs.ErrNotExist) {
+				// Not every user has a start menu of their own
+				return nil
+			}
+			return err
+		}
+
 		if info.IsDir() {
 			return nil
 		}
@@ -80,7 +89,14 @@ func collectApplicationsFrom(directory string, result *[]api.Item) error {
 				log.Printf("Could not load image for %s\n", path)
 			}
 
+			// The path within the start menu identifies the shortcut, e.g. "Accessories/Paint"
+			relative, err := filepath.Rel(directory, path)
+			if err != nil {
+				return err
+			}
+
 			*result = append(*result, api.Item{
+				ID:       filepath.ToSlash(strings.TrimSuffix(relative, extension)),
 				Label:    name[0 : len(name)-len(extension)],
 				Category: api.File,
 				Data:     path,
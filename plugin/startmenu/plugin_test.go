@@ -1,11 +1,67 @@
 package startmenu
 
-import "testing"
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
 
-func TestStartMenuPlugin_GetItems(t *testing.T) {
-	p := Plugin{}
-	items, _ := p.GetItems()
-	for _, each := range items {
-		println(each.Label)
+	"go-keyboard-launcher/api"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startMenu creates the start menu below root with the given files
+func startMenu(t *testing.T, root string, files ...string) {
+	for _, each := range files {
+		path := filepath.Join(root, "Microsoft", "Windows", "Start Menu", "Programs", each)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, nil, 0o644))
 	}
 }
+
+func TestStartMenuPlugin_Catalog(t *testing.T) {
+	programData, appData := t.TempDir(), t.TempDir()
+	t.Setenv("ProgramData", programData)
+	t.Setenv("AppData", appData)
+
+	startMenu(t, programData, "Outlook.lnk", "Accessories/Paint.lnk", "Accessories/desktop.ini")
+	startMenu(t, appData, "Documentation.url")
+
+	p := Plugin{}
+	var batches [][]string
+	err := p.Catalog(context.Background(), func(items []api.Item) {
+		var ids []string
+		for _, each := range items {
+			assert.Equal(t, api.File, each.Category)
+			assert.FileExists(t, each.Data.(string))
+			ids = append(ids, each.ID+"="+each.Label)
+		}
+
+		sort.Strings(ids)
+		batches = append(batches, ids)
+	})
+
+	// The items are identified by their path in the start menu, so they can be targeted by aliases
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"Accessories/Paint=Paint", "Outlook=Outlook"}, {"Documentation=Documentation"}}, batches)
+}
+
+func TestStartMenuPlugin_CatalogWithoutUserStartMenu(t *testing.T) {
+	programData := t.TempDir()
+	t.Setenv("ProgramData", programData)
+	t.Setenv("AppData", filepath.Join(t.TempDir(), "missing"))
+
+	startMenu(t, programData, "Outlook.lnk")
+
+	p := Plugin{}
+	count := 0
+	err := p.Catalog(context.Background(), func(items []api.Item) {
+		count += len(items)
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
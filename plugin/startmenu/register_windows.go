@@ -0,0 +1,14 @@
+//go:build windows
+
+package startmenu
+
+import (
+	"go-keyboard-launcher/api"
+)
+
+// The start menu only exists on Windows, so the plugin is only available there
+func init() {
+	api.Register("win32-apps", func(name string) api.Plugin {
+		return &Plugin{name: name}
+	})
+}
@@ -10,10 +10,16 @@ import (
 	"go-keyboard-launcher/api"
 
 	"github.com/hashicorp/go-hclog"
-	"golang.design/x/clipboard"
 )
 
+func init() {
+	api.Register("str", func(name string) api.Plugin {
+		return &Plugin{name: name}
+	})
+}
+
 type Plugin struct {
+	name string
 	log  hclog.Logger
 	icon *image.Image
 }
@@ -27,13 +33,22 @@ func (p *Plugin) LoadConfig(f func(interface{}) error) {
 	// No configuration to load
 }
 
-func (p *Plugin) Catalog(context.Context) error {
-	// Nothing to do here
+func (p *Plugin) Catalog(ctx context.Context, callback api.CatalogCallback) error {
+	callback([]api.Item{{
+		ID:          "base64",
+		Label:       "String: Base64",
+		Description: "",
+		Category:    api.User,
+		Target:      "",
+		Data:        nil,
+		Icon:        nil,
+		ArgsHint:    api.Required,
+	}})
 	return nil
 }
 
 func (p *Plugin) Name() string {
-	return "str"
+	return p.name
 }
 
 func (p *Plugin) loadIcon() {
@@ -58,18 +73,6 @@ func (p *Plugin) Icon() *image.Image {
 	return p.icon
 }
 
-func (p *Plugin) GetItems() ([]api.Item, error) {
-	return []api.Item{{
-		Label:       "String: Base64",
-		Description: "",
-		Category:    api.User,
-		Target:      "",
-		Data:        nil,
-		Icon:        nil,
-		ArgsHint:    api.Required,
-	}}, nil
-}
-
 func (p Plugin) Suggest(ctx context.Context, input string, chain []api.Item, callback api.SuggestionCallback) {
 	if len(chain) == 0 {
 		return
@@ -98,6 +101,6 @@ func (p Plugin) Suggest(ctx context.Context, input string, chain []api.Item, cal
 	callback(suggestions, api.MatchAny)
 }
 
-func (p Plugin) Execute(item api.Item) {
-	clipboard.Write(clipboard.FmtText, []byte(item.Target))
+func (p Plugin) Execute(ctx context.Context, item api.Item, action string) error {
+	return api.CopyToClipboard(item.Target)
 }
@@ -0,0 +1,225 @@
+// Package xdgapps catalogs the applications of the freedesktop desktop entries, the applications menu of Linux
+// desktops
+package xdgapps
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"go-keyboard-launcher/api"
+	"go-keyboard-launcher/api/freedesktop"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+// actionPrefix prefixes the IDs of the actions of the entries, so they cannot be confused with built-in actions
+const actionPrefix = "desktop-action:"
+
+type Plugin struct {
+	name string
+	log  hclog.Logger
+
+	mutex   sync.Mutex
+	entries map[string]freedesktop.Entry // The entries of the last catalog by desktop file ID
+}
+
+func (p *Plugin) Initialize(log hclog.Logger) {
+	p.log = log
+}
+
+func (p *Plugin) LoadConfig(f func(interface{}) error) {
+	// No configuration to load
+}
+
+// currentDesktops returns the names of the desktop environment for OnlyShowIn and NotShowIn, e.g. GNOME
+func currentDesktops() []string {
+	return strings.Split(os.Getenv("XDG_CURRENT_DESKTOP"), ":")
+}
+
+// ReadEntries returns the entries of the directories by their desktop file ID. An entry in an earlier directory hides
+// the entries with the same ID in the directories that follow, even if it is hidden itself.
+func ReadEntries(dirs []string, locales []string, log hclog.Logger) map[string]freedesktop.Entry {
+	result := make(map[string]freedesktop.Entry)
+
+	for _, dir := range dirs {
+		_ = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() || filepath.Ext(path) != ".desktop" {
+				return nil
+			}
+
+			// The ID of applications/vendor/app.desktop is vendor-app.desktop
+			relative, err := filepath.Rel(dir, path)
+			if err != nil {
+				return nil
+			}
+			id := strings.ReplaceAll(filepath.ToSlash(relative), "/", "-")
+
+			if _, found := result[id]; found {
+				return nil
+			}
+
+			entry, err := freedesktop.ReadEntry(path, id, locales)
+			if err != nil {
+				log.Warn("Ignoring desktop entry", "file", path, "error", err)
+				return nil
+			}
+
+			result[id] = entry
+			return nil
+		})
+	}
+
+	return result
+}
+
+// visible returns true if the entry is an application that is shown in the menu of the desktop
+func visible(e freedesktop.Entry, desktops []string) bool {
+	if e.Type != "Application" || e.Hidden || e.NoDisplay || len(e.Name) == 0 || len(e.Exec) == 0 {
+		return false
+	}
+
+	if len(e.OnlyShowIn) > 0 && !intersects(e.OnlyShowIn, desktops) {
+		return false
+	}
+	if intersects(e.NotShowIn, desktops) {
+		return false
+	}
+
+	if len(e.TryExec) > 0 {
+		if _, err := exec.LookPath(e.TryExec); err != nil {
+			return false
+		}
+	}
+
+	return true
+}
+
+func intersects(a []string, b []string) bool {
+	for _, x := range a {
+		for _, y := range b {
+			if strings.EqualFold(x, y) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func (p *Plugin) Catalog(ctx context.Context, callback api.CatalogCallback) error {
+	entries := ReadEntries(freedesktop.ApplicationDirs(), freedesktop.Locales(), p.log)
+	desktops := currentDesktops()
+
+	var items []api.Item
+	for _, e := range entries {
+		if visible(e, desktops) {
+			items = append(items, p.item(e))
+		}
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].Label < items[j].Label
+	})
+
+	p.mutex.Lock()
+	p.entries = entries
+	p.mutex.Unlock()
+
+	callback(items)
+	return nil
+}
+
+func (p *Plugin) item(e freedesktop.Entry) api.Item {
+	item := api.Item{
+		ID:          e.ID,
+		Label:       e.Name,
+		Description: e.GenericName,
+		Category:    api.User,
+		Target:      e.File,
+		Data:        e.ID,
+		ArgsHint:    api.Forbidden,
+		Keywords:    append([]string(nil), e.Keywords...),
+	}
+
+	if len(item.Description) == 0 {
+		item.Description = e.Comment
+	} else {
+		item.Keywords = append(item.Keywords, e.GenericName)
+	}
+
+	// The program is a keyword, e.g. "nautilus" finds Files
+	if program, found := e.Program(); found {
+		if program = filepath.Base(program); !strings.EqualFold(program, e.Name) {
+			item.Keywords = append(item.Keywords, program)
+		}
+	}
+
+	for _, action := range e.Actions {
+		item.Actions = append(item.Actions, api.Action{ID: actionPrefix + action.ID, Label: action.Name})
+	}
+
+	if icon, err := api.GetShellIconImage(e.File); err == nil {
+		item.Icon = &icon
+	}
+
+	return item
+}
+
+// entry returns the entry with the desktop file ID, it is read again if the catalog was loaded from the cache
+func (p *Plugin) entry(id string) (freedesktop.Entry, error) {
+	p.mutex.Lock()
+	e, found := p.entries[id]
+	p.mutex.Unlock()
+
+	if found {
+		return e, nil
+	}
+
+	e, found = ReadEntries(freedesktop.ApplicationDirs(), freedesktop.Locales(), p.log)[id]
+	if !found {
+		return freedesktop.Entry{}, fmt.Errorf("application %s is no longer installed", id)
+	}
+
+	return e, nil
+}
+
+// Execute launches the application, or the action of the application
+func (p *Plugin) Execute(ctx context.Context, item api.Item, action string) error {
+	id, ok := item.Data.(string)
+	if !ok {
+		return fmt.Errorf("I don't know how to execute item %s", item.String())
+	}
+
+	e, err := p.entry(id)
+	if err != nil {
+		return err
+	}
+
+	args, err := freedesktop.Command(e, strings.TrimPrefix(action, actionPrefix), nil)
+	if err != nil {
+		return err
+	}
+
+	p.log.Debug("Launching application", "id", id, "command", args)
+	return freedesktop.Launch(args, e.Path)
+}
+
+func (p *Plugin) Suggest(ctx context.Context, input string, chain []api.Item, callback api.SuggestionCallback) {
+	// The applications are in the catalog
+}
+
+func (p *Plugin) Icon() *image.Image {
+	return nil
+}
+
+func (p *Plugin) Name() string {
+	return p.name
+}
@@ -0,0 +1,110 @@
+package xdgapps
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"go-keyboard-launcher/api"
+	"go-keyboard-launcher/api/freedesktop"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// useFixtures points the XDG directories at the fixtures, the user directory overrides the system directories
+func useFixtures(t *testing.T, lang string) *Plugin {
+	t.Setenv("XDG_DATA_HOME", filepath.Join("testdata", "home"))
+	t.Setenv("XDG_DATA_DIRS", filepath.Join("testdata", "system")+string(filepath.ListSeparator)+filepath.Join("testdata", "other"))
+	t.Setenv("XDG_CURRENT_DESKTOP", "ubuntu:GNOME")
+	t.Setenv("LC_ALL", "")
+	t.Setenv("LC_MESSAGES", "")
+	t.Setenv("LANG", lang)
+	t.Setenv("TERMINAL", "test-terminal")
+
+	p := &Plugin{name: "linux-apps"}
+	p.Initialize(hclog.NewNullLogger())
+
+	return p
+}
+
+func catalog(t *testing.T, p *Plugin) map[string]api.Item {
+	result := make(map[string]api.Item)
+
+	require.NoError(t, p.Catalog(context.Background(), func(items []api.Item) {
+		for _, each := range items {
+			result[each.Label] = each
+		}
+	}))
+
+	return result
+}
+
+func TestPlugin_Catalog(t *testing.T) {
+	p := useFixtures(t, "en_US.UTF-8")
+	items := catalog(t, p)
+
+	var labels []string
+	for label := range items {
+		labels = append(labels, label)
+	}
+	assert.ElementsMatch(t, []string{"Firefox", "GNOME Tool", "Htop", "Overridden By The User", "Text Editor"}, labels)
+
+	firefox := items["Firefox"]
+	assert.Equal(t, "firefox.desktop", firefox.ID)
+	assert.Equal(t, "Web Browser", firefox.Description)
+	assert.Equal(t, []string{"Internet", "WWW", "Browser", "Web", "Explorer", "Web Browser"}, firefox.Keywords)
+	assert.Equal(t, []api.Action{
+		{ID: "desktop-action:new-window", Label: "New Window"},
+		{ID: "desktop-action:new-private-window", Label: "New Private Window"},
+	}, firefox.Actions)
+
+	// Without a generic name the comment describes the item, a program with the same name is no keyword
+	htop := items["Htop"]
+	assert.Equal(t, "Show System Processes", htop.Description)
+	assert.Empty(t, htop.Keywords)
+
+	// The program is a keyword
+	editor := items["Text Editor"]
+	assert.Equal(t, "vendor-editor.desktop", editor.ID)
+	assert.Equal(t, []string{"editor"}, editor.Keywords)
+}
+
+func TestPlugin_CatalogLocalized(t *testing.T) {
+	p := useFixtures(t, "de_DE.UTF-8")
+	items := catalog(t, p)
+
+	firefox := items["Firefox Webbrowser"]
+	assert.Equal(t, "Webbrowser", firefox.Description)
+	assert.Equal(t, []string{"Internet", "WWW", "Browser", "Netz", "Webbrowser", "firefox"}, firefox.Keywords)
+	assert.Equal(t, "Neues Fenster", firefox.Actions[0].Label)
+	assert.Equal(t, "New Private Window", firefox.Actions[1].Label, "falls back to the name without locale")
+}
+
+func TestCommand(t *testing.T) {
+	useFixtures(t, "en_US.UTF-8")
+	entries := ReadEntries(freedesktop.ApplicationDirs(), nil, hclog.NewNullLogger())
+
+	for _, tc := range []struct {
+		id       string
+		action   string
+		files    []string
+		expected []string
+	}{
+		{"firefox.desktop", "", nil, []string{"firefox"}},
+		{"firefox.desktop", "", []string{"https://example.com"}, []string{"firefox", "https://example.com"}},
+		{"firefox.desktop", "new-private-window", nil, []string{"firefox", "--private-window"}},
+		{"vendor-editor.desktop", "", nil, []string{"/opt/my editor/bin/editor", "--name=Text Editor", "--file=", "--icon", "editor"}},
+		{"vendor-editor.desktop", "", []string{"a.txt", "b.txt"}, []string{"/opt/my editor/bin/editor", "--name=Text Editor", "--file=a.txt", "a.txt", "b.txt", "--icon", "editor"}},
+		{"overridden.desktop", "", nil, []string{"sh", "-c", `echo "quoted $HOME"`}},
+		{"htop.desktop", "", nil, []string{"test-terminal", "-e", "htop"}},
+	} {
+		args, err := freedesktop.Command(entries[tc.id], tc.action, tc.files)
+		require.NoError(t, err, tc.id)
+		assert.Equal(t, tc.expected, args, tc.id)
+	}
+
+	_, err := freedesktop.Command(entries["firefox.desktop"], "unknown", nil)
+	assert.EqualError(t, err, "application firefox.desktop has no action unknown")
+}
@@ -0,0 +1,15 @@
+//go:build linux || freebsd || openbsd
+
+package xdgapps
+
+import (
+	"go-keyboard-launcher/api"
+)
+
+// Desktop entries are the application menu of the desktops that follow the freedesktop specifications, so the plugin
+// is only available where they are used
+func init() {
+	api.Register("linux-apps", func(name string) api.Plugin {
+		return &Plugin{name: name}
+	})
+}
@@ -1,12 +1,16 @@
 package main
 
 import (
+	"fmt"
 	"image"
 	"image/color"
 	"log"
+	"sort"
+	"strings"
 	"time"
 
 	"go-keyboard-launcher/api"
+	"go-keyboard-launcher/engine"
 
 	"gioui.org/app"
 	"gioui.org/io/key"
@@ -41,6 +45,8 @@ var colorHighlightItem = color.NRGBA{R: 0x2e, G: 0x64, B: 0x70, A: 0xff}
 //var colorText = color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
 var colorText = color.NRGBA{R: 0xdd, G: 0xDD, B: 0xDD, A: 0xff}
 var colorDescriptionText = color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0x33}
+var colorErrorText = color.NRGBA{R: 0xe0, G: 0x6c, B: 0x6c, A: 0xff}
+var colorMatchText = color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
 
 const ItemHeight = 26
 const SearchFontSize = 16
@@ -76,6 +82,9 @@ func (a *App) eventLoop(w *app.Window) error {
 				return nil
 			}
 
+		case binding := <-a.hotkeyChannel:
+			a.doHotkey(w, binding)
+
 		case evt := <-w.Events():
 			switch e := evt.(type) {
 			case system.DestroyEvent:
@@ -93,7 +102,10 @@ func (a *App) eventLoop(w *app.Window) error {
 				// React to events
 				a.handleInputEvents(gtx)
 
-				newItems := Min(len(a.suggestItems), 10)
+				suggestions := a.engine.Suggestions()
+				selectedIndex := a.engine.SelectedIndex()
+
+				newItems := Min(len(suggestions), 10)
 				if a.lastVisibleItems != newItems {
 					log.Printf("Resizing windows\n")
 					a.lastVisibleItems = newItems
@@ -113,12 +125,11 @@ func (a *App) eventLoop(w *app.Window) error {
 					},
 				), layout.Flexed(1,
 					func(gtx C) D {
-						dim := material.List(theme, &a.listWidget).Layout(gtx, len(a.suggestItems),
+						dim := material.List(theme, &a.listWidget).Layout(gtx, len(suggestions),
 							func(gtx C, index int) D {
 								gtx.Constraints.Max.Y = ItemHeight
 								gtx.Constraints.Min.Y = ItemHeight
-								item := a.suggestItems[index].Item
-								return drawItem(gtx, theme, item, index == a.suggestItemIndex)
+								return drawItem(gtx, theme, suggestions[index], index == selectedIndex)
 							},
 						)
 
@@ -151,7 +162,7 @@ func (a *App) handleInputEvents(gtx layout.Context) {
 	// Keyboard events
 	for _, event := range a.textInput.Events() {
 		if _, ok := event.(widget.ChangeEvent); ok {
-			a.Search(a.textInput.Text())
+			a.engine.Search(a.textInput.Text())
 		}
 	}
 
@@ -161,13 +172,14 @@ func (a *App) handleInputEvents(gtx layout.Context) {
 			switch e.Type {
 			// Handle single and double-clicks on list items
 			case pointer.Press:
-				lastIndex := a.suggestItemIndex
-				a.suggestItemIndex = a.listWidget.Position.First + int(e.Position.Y/24)
+				lastIndex := a.engine.SelectedIndex()
+				index := a.listWidget.Position.First + int(e.Position.Y/24)
+				a.engine.Select(index)
 
 				clickDelta := e.Time - a.lastClickTime
 				a.lastClickTime = e.Time
 
-				if lastIndex == a.suggestItemIndex && clickDelta < time.Millisecond*500 {
+				if lastIndex == index && clickDelta < time.Millisecond*500 {
 					// It's a double click, on the same item
 					a.enter()
 				}
@@ -185,39 +197,95 @@ func drawInputBox(gtx C, a *App, th *material.Theme) D {
 
 	return border.Layout(gtx,
 		func(gtx C) D {
-			if len(a.itemStack) > 0 {
-				return layout.Flex{}.Layout(gtx,
-					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
-						// Draw a stack
-						return layout.Stack{Alignment: layout.W}.Layout(gtx,
-							// First fill the background
-							layout.Expanded(func(gtx layout.Context) layout.Dimensions {
-								defer clip.Rect{Max: gtx.Constraints.Min}.Push(gtx.Ops).Pop()
-								paint.Fill(gtx.Ops, colorBorder)
-								return layout.Dimensions{Size: gtx.Constraints.Min}
-							}),
-
-							// Draw the label on top
-							layout.Stacked(func(gtx layout.Context) layout.Dimensions {
-								label := material.Label(th, unit.Sp(16), a.itemStack[len(a.itemStack)-1].item.DisplayName())
-								label.Color = colorText
-
-								return drawInset(gtx, func(gtx C) D {
-									return label.Layout(gtx)
-								})
-							}),
-						)
-					}),
-					layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
-						return drawInputTextField(gtx, a, th)
-					}),
-				)
-			} else {
+			var children []layout.FlexChild
+
+			stack := a.engine.Stack()
+
+			if len(stack) > 0 {
+				children = append(children, layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+					// Draw a stack
+					return layout.Stack{Alignment: layout.W}.Layout(gtx,
+						// First fill the background
+						layout.Expanded(func(gtx layout.Context) layout.Dimensions {
+							defer clip.Rect{Max: gtx.Constraints.Min}.Push(gtx.Ops).Pop()
+							paint.Fill(gtx.Ops, colorBorder)
+							return layout.Dimensions{Size: gtx.Constraints.Min}
+						}),
+
+						// Draw the label on top
+						layout.Stacked(func(gtx layout.Context) layout.Dimensions {
+							top := stack[len(stack)-1]
+							name := top.Item().DisplayName()
+							if top.IsActionMenu() {
+								name = "Actions: " + name
+							}
+
+							label := material.Label(th, unit.Sp(16), name)
+							label.Color = colorText
+
+							return drawInset(gtx, func(gtx C) D {
+								return label.Layout(gtx)
+							})
+						}),
+					)
+				}))
+			}
+
+			children = append(children, layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
 				return drawInputTextField(gtx, a, th)
+			}))
+
+			// Show which plugins are still building their catalog
+			if status := catalogStatus(a.engine.CatalogProgress()); len(status) > 0 {
+				children = append(children, layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+					label := material.Label(th, unit.Sp(ItemFontSize), status)
+					label.Color = colorDescriptionText
+					label.MaxLines = 1
+
+					return drawInset(gtx, func(gtx C) D {
+						return label.Layout(gtx)
+					})
+				}))
 			}
+
+			// Problems of the configuration are reported until they are fixed
+			if problems := a.ConfigProblems(); len(problems) > 0 {
+				children = append(children, layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+					status := problems[0].String()
+					if len(problems) > 1 {
+						status = fmt.Sprintf("%s (+%d more)", status, len(problems)-1)
+					}
+
+					label := material.Label(th, unit.Sp(ItemFontSize), status)
+					label.Color = colorErrorText
+					label.MaxLines = 1
+
+					return drawInset(gtx, func(gtx C) D {
+						return label.Layout(gtx)
+					})
+				}))
+			}
+
+			return layout.Flex{}.Layout(gtx, children...)
 		})
 }
 
+// catalogStatus describes the progress of the plugins that are building their catalog, e.g. "github: indexing… 340 items"
+func catalogStatus(progress map[string]int) string {
+	names := make([]string, 0, len(progress))
+	for name := range progress {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, len(names))
+	for idx, name := range names {
+		parts[idx] = fmt.Sprintf("%s: indexing… %d items", name, progress[name])
+	}
+
+	return strings.Join(parts, ", ")
+}
+
 func drawInputTextField(gtx C, a *App, th *material.Theme) layout.Dimensions {
 	return drawInset(gtx, func(gtx C) D {
 		return a.layoutEditor(gtx, th)
@@ -228,12 +296,25 @@ func drawInset(gtx C, f func(gtx C) D) layout.Dimensions {
 	return layout.UniformInset(unit.Dp(5)).Layout(gtx, f)
 }
 
-func drawItem(gtx C, th *material.Theme, item InternalItem, isHighlighted bool) D {
-	itemLabel := material.Label(th, unit.Sp(ItemFontSize), item.DisplayName())
-	itemLabel.Alignment = text.Start
-	itemLabel.Color = colorText
-	//itemLabel.Font.Weight = text.Thin
-	itemLabel.MaxLines = 1
+func drawItem(gtx C, th *material.Theme, suggestion engine.SuggestItem, isHighlighted bool) D {
+	item := suggestion.Item
+
+	// The label is drawn in segments, so the parts that matched the search can be highlighted
+	var labelChildren []layout.FlexChild
+	for _, segment := range labelSegments(item.DisplayName(), suggestion.Ranges) {
+		segmentLabel := material.Label(th, unit.Sp(ItemFontSize), segment.text)
+		segmentLabel.Alignment = text.Start
+		segmentLabel.Color = colorText
+		if item.Item.Category == api.Error {
+			segmentLabel.Color = colorErrorText
+		} else if segment.matched {
+			segmentLabel.Color = colorMatchText
+			segmentLabel.Font.Weight = text.Bold
+		}
+		segmentLabel.MaxLines = 1
+
+		labelChildren = append(labelChildren, layout.Rigid(segmentLabel.Layout))
+	}
 
 	descriptionLabel := material.Label(th, unit.Sp(ItemFontSize), item.Description())
 	descriptionLabel.MaxLines = 1
@@ -276,7 +357,7 @@ func drawItem(gtx C, th *material.Theme, item InternalItem, isHighlighted bool)
 		}), layout.Rigid(func(gtx layout.Context) layout.Dimensions {
 			// The label with some offset
 			op.Offset(image.Point{X: 7, Y: 1}).Add(gtx.Ops)
-			return itemLabel.Layout(gtx)
+			return layout.Flex{Axis: layout.Horizontal}.Layout(gtx, labelChildren...)
 		}), layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
 			// The description label
 			return layout.Inset{
@@ -298,13 +379,20 @@ func (a *App) layoutEditor(gtx C, th *material.Theme) D {
 	editor.Color = colorText
 	editor.TextSize = SearchFontSize
 
-	key.InputOp{Tag: &a.eventKey, Keys: "⎋|↓|↑|⏎|⌤|Tab|⌫"}.Add(gtx.Ops)
+	key.InputOp{Tag: &a.eventKey, Keys: "⎋|↓|↑|⏎|⌤|Tab|⌫|Ctrl-[⏎,⌤]|Alt-[⏎,⌤]|Ctrl-K"}.Add(gtx.Ops)
 
 	for _, e := range gtx.Events(&a.eventKey) {
 		switch ev := e.(type) {
 		case key.Event:
 			if ev.State == key.Press {
 				switch {
+				case (ev.Name == key.NameReturn || ev.Name == key.NameEnter) && ev.Modifiers.Contain(key.ModCtrl):
+					// Secondary actions
+					a.engine.ExecuteAction(1)
+				case (ev.Name == key.NameReturn || ev.Name == key.NameEnter) && ev.Modifiers.Contain(key.ModAlt):
+					a.engine.ExecuteAction(2)
+				case ev.Name == "K" && ev.Modifiers.Contain(key.ModCtrl):
+					a.engine.ShowActions()
 				case ev.Name == key.NameReturn:
 					a.enter()
 				case ev.Name == key.NameEscape:
@@ -316,7 +404,7 @@ func (a *App) layoutEditor(gtx C, th *material.Theme) D {
 				case ev.Name == key.NameTab:
 					a.itemSuggest()
 				case ev.Name == key.NameDeleteBackward:
-					if len(a.itemStack) > 0 {
+					if len(a.engine.Stack()) > 0 {
 						a.cancel()
 					}
 				}
@@ -324,5 +412,39 @@ func (a *App) layoutEditor(gtx C, th *material.Theme) D {
 		}
 	}
 
+	a.syncInput()
+
 	return editor.Layout(gtx)
 }
+
+type labelSegment struct {
+	text    string
+	matched bool
+}
+
+// labelSegments splits the label in the parts that did and did not match the search, the ranges are in runes
+func labelSegments(label string, ranges []engine.Range) []labelSegment {
+	runes := []rune(label)
+
+	var result []labelSegment
+	last := 0
+
+	for _, r := range ranges {
+		if r.Start < last || r.End > len(runes) {
+			// The ranges do not belong to this label, draw it as a whole
+			return []labelSegment{{text: label}}
+		}
+
+		if r.Start > last {
+			result = append(result, labelSegment{text: string(runes[last:r.Start])})
+		}
+		result = append(result, labelSegment{text: string(runes[r.Start:r.End]), matched: true})
+		last = r.End
+	}
+
+	if last < len(runes) || len(result) == 0 {
+		result = append(result, labelSegment{text: string(runes[last:])})
+	}
+
+	return result
+}